@@ -0,0 +1,110 @@
+package gosol
+
+import "errors"
+
+var ErrNotEnumerable = errors.New("mapping is not enumerable")
+
+// EnumerableMapping is a Mapping which keeps track of its keys, listed in insertion order
+type EnumerableMapping[K comparable, V any] interface {
+	Mapping[K, V]
+	// Len returns the number of keys
+	Len() int
+	// Keys returns the keys in the order they were first set
+	Keys() []K
+	// Has returns whether key was set and not deleted
+	Has(key K) bool
+	// Delete removes key, which is set again at the end of the order if it is set later
+	Delete(key K) error
+}
+
+// positionedMapping is implemented by the EnumerableMappings which can restore a deleted key at its position in the order
+type positionedMapping[K comparable, V any] interface {
+	// position returns the position of key, as understood by insert
+	position(key K) (int, bool)
+	// insert sets key, which must not be set, at position i of the order
+	insert(i int, key K, value V) error
+}
+
+type enumerableMapping[K comparable, V any] struct {
+	keys  []K
+	index map[K]int
+	m     map[K]V
+}
+
+func (m *enumerableMapping[K, V]) Get(key K) (value V, err error) {
+	value, _ = m.m[key]
+	return value, nil
+}
+
+func (m *enumerableMapping[K, V]) Set(key K, value V) error {
+	if _, ok := m.index[key]; !ok {
+		m.index[key] = len(m.keys)
+		m.keys = append(m.keys, key)
+	}
+	m.m[key] = value
+	return nil
+}
+
+func (m *enumerableMapping[K, V]) Len() int {
+	return len(m.keys)
+}
+
+func (m *enumerableMapping[K, V]) Keys() []K {
+	return append([]K(nil), m.keys...)
+}
+
+func (m *enumerableMapping[K, V]) Has(key K) bool {
+	_, ok := m.index[key]
+	return ok
+}
+
+func (m *enumerableMapping[K, V]) Delete(key K) error {
+	i, ok := m.index[key]
+	if !ok {
+		return nil
+	}
+	copy(m.keys[i:], m.keys[i+1:])
+	m.keys = m.keys[:len(m.keys)-1]
+	for j := i; j < len(m.keys); j++ {
+		m.index[m.keys[j]] = j
+	}
+	delete(m.index, key)
+	delete(m.m, key)
+	return nil
+}
+
+func (m *enumerableMapping[K, V]) position(key K) (int, bool) {
+	i, ok := m.index[key]
+	return i, ok
+}
+
+func (m *enumerableMapping[K, V]) insert(i int, key K, value V) error {
+	if _, ok := m.index[key]; ok || i > len(m.keys) {
+		return m.Set(key, value)
+	}
+	m.keys = append(m.keys, key)
+	copy(m.keys[i+1:], m.keys[i:])
+	m.keys[i] = key
+	for j := i; j < len(m.keys); j++ {
+		m.index[m.keys[j]] = j
+	}
+	m.m[key] = value
+	return nil
+}
+
+// NewEnumerableMapping creates a map backed EnumerableMapping
+func NewEnumerableMapping[K comparable, V any]() EnumerableMapping[K, V] {
+	return &enumerableMapping[K, V]{
+		index: make(map[K]int),
+		m:     make(map[K]V),
+	}
+}
+
+// Enumerate returns m as an EnumerableMapping, or ErrNotEnumerable if it does not keep track of its keys
+func Enumerate[K comparable, V any](m Mapping[K, V]) (EnumerableMapping[K, V], error) {
+	enumerable, ok := m.(EnumerableMapping[K, V])
+	if !ok {
+		return nil, ErrNotEnumerable
+	}
+	return enumerable, nil
+}
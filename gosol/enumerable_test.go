@@ -0,0 +1,90 @@
+package gosol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumerableMapping(t *testing.T) {
+	m := NewEnumerableMapping[string, int]()
+	require.NoError(t, m.Set("c", 1))
+	require.NoError(t, m.Set("a", 2))
+	require.NoError(t, m.Set("b", 3))
+	require.NoError(t, m.Set("c", 4))
+	require.Equal(t, []string{"c", "a", "b"}, m.Keys())
+
+	require.NoError(t, m.Delete("c"))
+	require.False(t, m.Has("c"))
+	require.Equal(t, 2, m.Len())
+	require.NoError(t, m.Set("c", 5))
+	require.Equal(t, []string{"a", "b", "c"}, m.Keys())
+
+	// setting a key to the zero value keeps it
+	require.NoError(t, m.Set("a", 0))
+	require.True(t, m.Has("a"))
+
+	_, err := Enumerate(NewMapMapping[string, int]())
+	require.ErrorIs(t, err, ErrNotEnumerable)
+}
+
+func TestJournaledEnumerableMapping(t *testing.T) {
+	journal := NewJournal()
+	m, err := Enumerate(NewJournaledMapping(journal, NewEnumerableMapping[string, int]()))
+	require.NoError(t, err)
+	require.NoError(t, m.Set("a", 1))
+	require.NoError(t, m.Set("b", 2))
+
+	snapshot := journal.Snapshot()
+	require.NoError(t, m.Set("c", 3))
+	require.NoError(t, m.Delete("a"))
+	require.Equal(t, []string{"b", "c"}, m.Keys())
+
+	require.NoError(t, journal.RevertToSnapshot(snapshot))
+	require.False(t, m.Has("c"))
+	a, _ := m.Get("a")
+	require.Equal(t, 1, a)
+	require.Equal(t, 2, m.Len())
+	// deleted keys are restored at their position
+	require.Equal(t, []string{"a", "b"}, m.Keys())
+
+	for name, inner := range map[string]EnumerableMapping[string, int]{
+		"rwmutex": NewRWMutexEnumerableMapping[string, int](),
+		"overlay": NewOverlayMapping[string, int](NewEnumerableMapping[string, int](), nil).(EnumerableMapping[string, int]),
+	} {
+		t.Run(name, func(t *testing.T) {
+			journal := NewJournal()
+			m, err := Enumerate(NewJournaledMapping(journal, inner))
+			require.NoError(t, err)
+			require.NoError(t, m.Set("a", 1))
+			require.NoError(t, m.Set("b", 2))
+			require.NoError(t, m.Set("c", 3))
+
+			snapshot := journal.Snapshot()
+			require.NoError(t, m.Delete("a"))
+			require.NoError(t, m.Delete("b"))
+			require.Equal(t, []string{"c"}, m.Keys())
+			require.NoError(t, journal.RevertToSnapshot(snapshot))
+			require.Equal(t, []string{"a", "b", "c"}, m.Keys())
+		})
+	}
+}
+
+func TestOverlayEnumerableMapping(t *testing.T) {
+	base := NewEnumerableMapping[string, int]()
+	require.NoError(t, base.Set("a", 1))
+	require.NoError(t, base.Set("b", 2))
+
+	overlay, err := Enumerate(NewOverlayMapping[string, int](base, nil))
+	require.NoError(t, err)
+	require.NoError(t, overlay.Set("c", 3))
+	require.NoError(t, overlay.Delete("a"))
+	require.NoError(t, overlay.Set("b", 4))
+	require.Equal(t, []string{"b", "c"}, overlay.Keys())
+	a, _ := overlay.Get("a")
+	require.Equal(t, 0, a)
+
+	require.Equal(t, []string{"a", "b"}, base.Keys())
+	b, _ := base.Get("b")
+	require.Equal(t, 2, b)
+}
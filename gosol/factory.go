@@ -0,0 +1,50 @@
+package gosol
+
+// MappingKind is the implementation of the mappings created by a MappingFactory
+type MappingKind int
+
+const (
+	// MapKind mappings are backed by a plain map, and are not safe for concurrent use
+	MapKind MappingKind = iota
+	// RWMutexKind mappings are backed by a map guarded by a RWMutex
+	RWMutexKind
+	// ShardedKind mappings are backed by several maps guarded by their own RWMutex
+	ShardedKind
+)
+
+// DefaultShards is the number of shards of ShardedKind mappings when MappingFactory.Shards is not set
+const DefaultShards = 32
+
+// MappingFactory describes how to create the mappings of a contract, see MakeMapping and MakeEnumerableMapping.
+// the zero value creates plain map backed mappings.
+type MappingFactory struct {
+	Kind   MappingKind
+	Shards int
+}
+
+// MakeMapping creates a mapping of the kind described by f
+func MakeMapping[K comparable, V any](f MappingFactory) Mapping[K, V] {
+	switch f.Kind {
+	case RWMutexKind:
+		return NewRWMutexMapping[K, V]()
+	case ShardedKind:
+		shards := f.Shards
+		if shards == 0 {
+			shards = DefaultShards
+		}
+		return NewShardedMapping[K, V](shards)
+	default:
+		return NewMapMapping[K, V]()
+	}
+}
+
+// MakeEnumerableMapping creates an EnumerableMapping of the kind described by f.
+// since the insertion order is global, ShardedKind falls back to a single RWMutex.
+func MakeEnumerableMapping[K comparable, V any](f MappingFactory) EnumerableMapping[K, V] {
+	switch f.Kind {
+	case RWMutexKind, ShardedKind:
+		return NewRWMutexEnumerableMapping[K, V]()
+	default:
+		return NewEnumerableMapping[K, V]()
+	}
+}
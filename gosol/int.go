@@ -0,0 +1,279 @@
+package gosol
+
+import (
+	"math/big"
+
+	"github.com/holiman/uint256"
+)
+
+var (
+	maxUint128 = new(uint256.Int).SubUint64(new(uint256.Int).Lsh(uint256.NewInt(1), 128), 1)
+	minInt256  = new(uint256.Int).Lsh(uint256.NewInt(1), 255)
+	minusOne   = new(uint256.Int).SetAllOne()
+
+	// WadInt256 is 1e18 as an int256, the unit of the signed wad math
+	WadInt256 = NewInt256(1e18)
+)
+
+// SafeCast errors, based on the SafeCast.sol of OpenZeppelin
+func safeCastOverflowedUintDowncast(bits uint8, value *uint256.Int) error {
+	err, _ := NewCustomError("SafeCastOverflowedUintDowncast(uint8,uint256)", bits, value.ToBig())
+	return err
+}
+
+func safeCastOverflowedIntToUint(value Int256) error {
+	err, _ := NewCustomError("SafeCastOverflowedIntToUint(int256)", value.ToBig())
+	return err
+}
+
+func safeCastOverflowedUintToInt(value *uint256.Int) error {
+	err, _ := NewCustomError("SafeCastOverflowedUintToInt(uint256)", value.ToBig())
+	return err
+}
+
+// Uint128 is an unsigned 128-bit integer, with checked arithmetic mirroring solidity >=0.8
+type Uint128 struct {
+	v uint256.Int
+}
+
+// ToUint128 mirrors SafeCast.toUint128, failing if x does not fit in 128 bits
+func ToUint128(x *uint256.Int) (Uint128, error) {
+	if x.Gt(maxUint128) {
+		return Uint128{}, safeCastOverflowedUintDowncast(128, x)
+	}
+	return Uint128{v: *x}, nil
+}
+
+// Uint256 returns x as a uint256, which never fails
+func (x Uint128) Uint256() *uint256.Int {
+	return new(uint256.Int).Set(&x.v)
+}
+
+// Add returns x + y, panicking with PanicArithmetic on overflow
+func (x Uint128) Add(y Uint128) (Uint128, error) {
+	z := new(uint256.Int).Add(&x.v, &y.v)
+	if z.Gt(maxUint128) {
+		return Uint128{}, Panic(PanicArithmetic)
+	}
+	return Uint128{v: *z}, nil
+}
+
+// Sub returns x - y, panicking with PanicArithmetic on underflow
+func (x Uint128) Sub(y Uint128) (Uint128, error) {
+	if x.v.Lt(&y.v) {
+		return Uint128{}, Panic(PanicArithmetic)
+	}
+	return Uint128{v: *new(uint256.Int).Sub(&x.v, &y.v)}, nil
+}
+
+func (x Uint128) IsZero() bool {
+	return x.v.IsZero()
+}
+
+func (x Uint128) Cmp(y Uint128) int {
+	return x.v.Cmp(&y.v)
+}
+
+func (x Uint128) String() string {
+	return x.v.Dec()
+}
+
+// Int256 is a signed 256-bit integer in two's complement, with checked arithmetic mirroring solidity >=0.8
+type Int256 struct {
+	v uint256.Int
+}
+
+func NewInt256(x int64) Int256 {
+	z := Int256{v: *uint256.NewInt(uint64(x))}
+	if x < 0 {
+		z.v.Neg(uint256.NewInt(uint64(-x)))
+	}
+	return z
+}
+
+// MustInt256FromDecimal parses a signed decimal string, panicking if it is invalid or out of range
+func MustInt256FromDecimal(s string) Int256 {
+	b, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid int256 " + s)
+	}
+	z, err := Int256FromBig(b)
+	if err != nil {
+		panic(err)
+	}
+	return z
+}
+
+// Int256FromBig converts b, failing with PanicArithmetic if it is out of range
+func Int256FromBig(b *big.Int) (Int256, error) {
+	abs, overflow := uint256.FromBig(new(big.Int).Abs(b))
+	if overflow || abs.Gt(minInt256) || (b.Sign() >= 0 && abs.Eq(minInt256)) {
+		return Int256{}, Panic(PanicArithmetic)
+	}
+	if b.Sign() < 0 {
+		abs.Neg(abs)
+	}
+	return Int256{v: *abs}, nil
+}
+
+// Int256FromBits reinterprets the two's complement bits of x, mirroring the unchecked conversion int256(x)
+func Int256FromBits(x *uint256.Int) Int256 {
+	return Int256{v: *x}
+}
+
+// ToInt256 mirrors SafeCast.toInt256, failing if x is greater than the maximum int256
+func ToInt256(x *uint256.Int) (Int256, error) {
+	if !x.Lt(minInt256) {
+		return Int256{}, safeCastOverflowedUintToInt(x)
+	}
+	return Int256{v: *x}, nil
+}
+
+// ToUint256 mirrors SafeCast.toUint256, failing if x is negative
+func (x Int256) ToUint256() (*uint256.Int, error) {
+	if x.Sign() < 0 {
+		return nil, safeCastOverflowedIntToUint(x)
+	}
+	return new(uint256.Int).Set(&x.v), nil
+}
+
+// Bits returns the two's complement bits of x, mirroring the unchecked conversion uint256(x)
+func (x Int256) Bits() *uint256.Int {
+	return new(uint256.Int).Set(&x.v)
+}
+
+func (x Int256) ToBig() *big.Int {
+	if x.Sign() < 0 {
+		abs := new(uint256.Int).Neg(&x.v).ToBig()
+		return abs.Neg(abs)
+	}
+	return x.v.ToBig()
+}
+
+func (x Int256) Sign() int {
+	return x.v.Sign()
+}
+
+func (x Int256) IsZero() bool {
+	return x.v.IsZero()
+}
+
+func (x Int256) Cmp(y Int256) int {
+	switch {
+	case x.v.Slt(&y.v):
+		return -1
+	case x.v.Sgt(&y.v):
+		return 1
+	}
+	return 0
+}
+
+func (x Int256) Lt(y Int256) bool {
+	return x.v.Slt(&y.v)
+}
+
+func (x Int256) Gt(y Int256) bool {
+	return x.v.Sgt(&y.v)
+}
+
+func (x Int256) String() string {
+	return x.ToBig().String()
+}
+
+// Add returns x + y, panicking with PanicArithmetic on overflow
+func (x Int256) Add(y Int256) (Int256, error) {
+	z := Int256{}
+	z.v.Add(&x.v, &y.v)
+	// overflows iff both operands have the same sign, and the result has the other one
+	xNegative, yNegative, zNegative := x.Sign() < 0, y.Sign() < 0, z.Sign() < 0
+	if xNegative == yNegative && xNegative != zNegative {
+		return Int256{}, Panic(PanicArithmetic)
+	}
+	return z, nil
+}
+
+// Sub returns x - y, panicking with PanicArithmetic on overflow
+func (x Int256) Sub(y Int256) (Int256, error) {
+	z := Int256{}
+	z.v.Sub(&x.v, &y.v)
+	// overflows iff the operands have different signs, and the result has the sign of y
+	xNegative, yNegative, zNegative := x.Sign() < 0, y.Sign() < 0, z.Sign() < 0
+	if xNegative != yNegative && xNegative != zNegative {
+		return Int256{}, Panic(PanicArithmetic)
+	}
+	return z, nil
+}
+
+// Mul returns x * y, panicking with PanicArithmetic on overflow
+func (x Int256) Mul(y Int256) (Int256, error) {
+	abs, overflow := new(uint256.Int).MulOverflow(x.abs(), y.abs())
+	negative := (x.Sign() < 0) != (y.Sign() < 0)
+	if overflow || abs.Gt(minInt256) || (!negative && abs.Eq(minInt256)) {
+		return Int256{}, Panic(PanicArithmetic)
+	}
+	if negative {
+		abs.Neg(abs)
+	}
+	return Int256{v: *abs}, nil
+}
+
+// Div returns x / y rounded towards zero, panicking with PanicDivisionByZero if y is zero,
+// and with PanicArithmetic for the minimum int256 divided by -1
+func (x Int256) Div(y Int256) (Int256, error) {
+	if y.IsZero() {
+		return Int256{}, Panic(PanicDivisionByZero)
+	}
+	if x.v.Eq(minInt256) && y.v.Eq(minusOne) {
+		return Int256{}, Panic(PanicArithmetic)
+	}
+	z := Int256{}
+	z.v.SDiv(&x.v, &y.v)
+	return z, nil
+}
+
+// Neg returns -x, panicking with PanicArithmetic for the minimum int256
+func (x Int256) Neg() (Int256, error) {
+	if x.v.Eq(minInt256) {
+		return Int256{}, Panic(PanicArithmetic)
+	}
+	z := Int256{}
+	z.v.Neg(&x.v)
+	return z, nil
+}
+
+// abs returns |x| as a uint256, which holds the absolute value of the minimum int256
+func (x Int256) abs() *uint256.Int {
+	if x.Sign() < 0 {
+		return new(uint256.Int).Neg(&x.v)
+	}
+	return new(uint256.Int).Set(&x.v)
+}
+
+// WMulToZero returns (x * y) / WAD rounded towards zero, mirroring MathLib.wMulToZero of morpho-blue-irm
+func WMulToZero(x, y Int256) (Int256, error) {
+	z, err := x.Mul(y)
+	if err != nil {
+		return Int256{}, err
+	}
+	return z.Div(WadInt256)
+}
+
+// WDivToZero returns (x * WAD) / y rounded towards zero, mirroring MathLib.wDivToZero of morpho-blue-irm
+func WDivToZero(x, y Int256) (Int256, error) {
+	z, err := x.Mul(WadInt256)
+	if err != nil {
+		return Int256{}, err
+	}
+	return z.Div(y)
+}
+
+// Bound returns x bounded between low and high, mirroring UtilsLib.bound of morpho-blue-irm
+func Bound(x, low, high Int256) Int256 {
+	if x.Lt(low) {
+		x = low
+	}
+	if high.Lt(x) {
+		x = high
+	}
+	return x
+}
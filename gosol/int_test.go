@@ -0,0 +1,100 @@
+package gosol
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUint128(t *testing.T) {
+	max, err := ToUint128(maxUint128)
+	require.NoError(t, err)
+	one, err := ToUint128(uint256.NewInt(1))
+	require.NoError(t, err)
+
+	_, err = ToUint128(new(uint256.Int).AddUint64(maxUint128, 1))
+	require.ErrorIs(t, err, &CustomError{Selector: Selector("SafeCastOverflowedUintDowncast(uint8,uint256)")})
+
+	_, err = max.Add(one)
+	require.ErrorIs(t, err, Panic(PanicArithmetic))
+	_, err = Uint128{}.Sub(one)
+	require.ErrorIs(t, err, Panic(PanicArithmetic))
+
+	z, err := max.Sub(one)
+	require.NoError(t, err)
+	z, err = z.Add(one)
+	require.NoError(t, err)
+	require.Equal(t, 0, z.Cmp(max))
+	require.Equal(t, "340282366920938463463374607431768211455", z.String())
+}
+
+func TestInt256(t *testing.T) {
+	maxInt256 := MustInt256FromDecimal("57896044618658097711785492504343953926634992332820282019728792003956564819967")
+	minInt256 := MustInt256FromDecimal("-57896044618658097711785492504343953926634992332820282019728792003956564819968")
+	one, minusOne := NewInt256(1), NewInt256(-1)
+
+	require.Panics(t, func() {
+		MustInt256FromDecimal("57896044618658097711785492504343953926634992332820282019728792003956564819968")
+	})
+	require.Equal(t, "-1", minusOne.String())
+	require.True(t, minInt256.Lt(maxInt256))
+	require.Equal(t, 1, one.Cmp(minusOne))
+
+	for name, op := range map[string]func() (Int256, error){
+		"max + 1":  func() (Int256, error) { return maxInt256.Add(one) },
+		"min - 1":  func() (Int256, error) { return minInt256.Sub(one) },
+		"max - -1": func() (Int256, error) { return maxInt256.Sub(minusOne) },
+		"min * -1": func() (Int256, error) { return minInt256.Mul(minusOne) },
+		"max * 2":  func() (Int256, error) { return maxInt256.Mul(NewInt256(2)) },
+		"min / -1": func() (Int256, error) { return minInt256.Div(minusOne) },
+		"-min":     func() (Int256, error) { return minInt256.Neg() },
+	} {
+		_, err := op()
+		require.ErrorIs(t, err, Panic(PanicArithmetic), name)
+	}
+	_, err := one.Div(NewInt256(0))
+	require.ErrorIs(t, err, Panic(PanicDivisionByZero))
+
+	// the minimum is reachable
+	z, err := maxInt256.Neg()
+	require.NoError(t, err)
+	z, err = z.Add(minusOne)
+	require.NoError(t, err)
+	require.Equal(t, minInt256, z)
+	z, err = minInt256.Div(NewInt256(2))
+	require.NoError(t, err)
+	z, err = z.Mul(NewInt256(2))
+	require.NoError(t, err)
+	require.Equal(t, minInt256, z)
+
+	// division rounds towards zero
+	z, err = NewInt256(-7).Div(NewInt256(2))
+	require.NoError(t, err)
+	require.Equal(t, "-3", z.String())
+
+	_, err = ToInt256(maxInt256.Bits().AddUint64(maxInt256.Bits(), 1))
+	require.ErrorIs(t, err, &CustomError{Selector: Selector("SafeCastOverflowedUintToInt(uint256)")})
+	_, err = minusOne.ToUint256()
+	require.ErrorIs(t, err, &CustomError{Selector: Selector("SafeCastOverflowedIntToUint(int256)")})
+	require.Equal(t, uint256.NewInt(0).SetAllOne(), minusOne.Bits())
+}
+
+func TestWadInt256(t *testing.T) {
+	half := MustInt256FromDecimal("500000000000000000")
+
+	z, err := WMulToZero(NewInt256(-3), half)
+	require.NoError(t, err)
+	require.Equal(t, "-1", z.String())
+
+	z, err = WDivToZero(NewInt256(-1), NewInt256(3))
+	require.NoError(t, err)
+	require.Equal(t, "-333333333333333333", z.String())
+
+	_, err = WDivToZero(NewInt256(1), NewInt256(0))
+	require.ErrorIs(t, err, Panic(PanicDivisionByZero))
+
+	require.Equal(t, NewInt256(1), Bound(NewInt256(-5), NewInt256(1), NewInt256(10)))
+	require.Equal(t, NewInt256(10), Bound(NewInt256(50), NewInt256(1), NewInt256(10)))
+	require.Equal(t, NewInt256(5), Bound(NewInt256(5), NewInt256(1), NewInt256(10)))
+}
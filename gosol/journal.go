@@ -0,0 +1,179 @@
+package gosol
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+type revision struct {
+	id           int
+	journalIndex int
+}
+
+// Journal records how to undo the changes made to journaled state, so that they can be reverted to a snapshot.
+// snapshots nest like the StateDB journal of go-ethereum: reverting to a snapshot invalidates the snapshots taken after it.
+type Journal struct {
+	entries        []func() error
+	validRevisions []revision
+	nextRevisionId int
+}
+
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// Append records undo, to be called when reverting past the current point of the journal
+func (j *Journal) Append(undo func() error) {
+	j.entries = append(j.entries, undo)
+}
+
+// Snapshot returns an identifier for the current state
+func (j *Journal) Snapshot() int {
+	id := j.nextRevisionId
+	j.nextRevisionId++
+	j.validRevisions = append(j.validRevisions, revision{id, len(j.entries)})
+	return id
+}
+
+// RevertToSnapshot undoes all changes recorded since the snapshot with the given id was taken
+func (j *Journal) RevertToSnapshot(id int) error {
+	idx := sort.Search(len(j.validRevisions), func(i int) bool {
+		return j.validRevisions[i].id >= id
+	})
+	if idx == len(j.validRevisions) || j.validRevisions[idx].id != id {
+		return fmt.Errorf("%w: %d", ErrInvalidSnapshot, id)
+	}
+	journalIndex := j.validRevisions[idx].journalIndex
+	j.validRevisions = j.validRevisions[:idx]
+
+	var errs []error
+	for i := len(j.entries) - 1; i >= journalIndex; i-- {
+		if err := j.entries[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	j.entries = j.entries[:journalIndex]
+	return errors.Join(errs...)
+}
+
+// DiscardSnapshot forgets the snapshot with the given id, keeping the changes made since it was taken.
+// recorded changes are dropped once no snapshot is left to revert them to.
+func (j *Journal) DiscardSnapshot(id int) {
+	for i := len(j.validRevisions) - 1; i >= 0; i-- {
+		if j.validRevisions[i].id == id {
+			j.validRevisions = append(j.validRevisions[:i], j.validRevisions[i+1:]...)
+			break
+		}
+	}
+	if len(j.validRevisions) == 0 {
+		j.entries = nil
+	}
+}
+
+// Reset discards all recorded changes and snapshots, making the current state final
+func (j *Journal) Reset() {
+	j.entries = nil
+	j.validRevisions = nil
+}
+
+type journaledMapping[K comparable, V any] struct {
+	journal *Journal
+	inner   Mapping[K, V]
+}
+
+func (m *journaledMapping[K, V]) Get(key K) (value V, err error) {
+	return m.inner.Get(key)
+}
+
+func (m *journaledMapping[K, V]) Set(key K, value V) error {
+	prev, err := m.inner.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := m.inner.Set(key, value); err != nil {
+		return err
+	}
+	m.journal.Append(func() error {
+		return m.inner.Set(key, prev)
+	})
+	return nil
+}
+
+type journaledEnumerableMapping[K comparable, V any] struct {
+	journaledMapping[K, V]
+	inner EnumerableMapping[K, V]
+}
+
+func (m *journaledEnumerableMapping[K, V]) Set(key K, value V) error {
+	if m.inner.Has(key) {
+		return m.journaledMapping.Set(key, value)
+	}
+	if err := m.inner.Set(key, value); err != nil {
+		return err
+	}
+	m.journal.Append(func() error {
+		return m.inner.Delete(key)
+	})
+	return nil
+}
+
+func (m *journaledEnumerableMapping[K, V]) Len() int {
+	return m.inner.Len()
+}
+
+func (m *journaledEnumerableMapping[K, V]) Keys() []K {
+	return m.inner.Keys()
+}
+
+func (m *journaledEnumerableMapping[K, V]) Has(key K) bool {
+	return m.inner.Has(key)
+}
+
+func (m *journaledEnumerableMapping[K, V]) Delete(key K) error {
+	if !m.inner.Has(key) {
+		return nil
+	}
+	prev, err := m.inner.Get(key)
+	if err != nil {
+		return err
+	}
+	// the key is restored at its position, otherwise it would be set again at the end of the order
+	positioned, ok := m.inner.(positionedMapping[K, V])
+	var i int
+	if ok {
+		i, ok = positioned.position(key)
+	}
+	if err := m.inner.Delete(key); err != nil {
+		return err
+	}
+	m.journal.Append(func() error {
+		if ok {
+			return positioned.insert(i, key, prev)
+		}
+		return m.inner.Set(key, prev)
+	})
+	return nil
+}
+
+// NewJournaledMapping wraps inner so that every Set is recorded in journal.
+// the returned mapping is an EnumerableMapping if inner is one, in which case Delete is recorded too.
+func NewJournaledMapping[K comparable, V any](journal *Journal, inner Mapping[K, V]) Mapping[K, V] {
+	if enumerable, ok := inner.(EnumerableMapping[K, V]); ok {
+		return &journaledEnumerableMapping[K, V]{
+			journaledMapping: journaledMapping[K, V]{journal: journal, inner: inner},
+			inner:            enumerable,
+		}
+	}
+	return &journaledMapping[K, V]{
+		journal: journal,
+		inner:   inner,
+	}
+}
+
+// NewJournaledMapMapping creates a map backed mapping whose changes are recorded in journal
+func NewJournaledMapMapping[K comparable, V any](journal *Journal) Mapping[K, V] {
+	return NewJournaledMapping(journal, NewMapMapping[K, V]())
+}
@@ -0,0 +1,47 @@
+package gosol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	journal := NewJournal()
+	m := NewJournaledMapMapping[string, int](journal)
+
+	require.NoError(t, m.Set("a", 1))
+	outer := journal.Snapshot()
+	require.NoError(t, m.Set("a", 2))
+	require.NoError(t, m.Set("b", 3))
+	inner := journal.Snapshot()
+	require.NoError(t, m.Set("a", 4))
+
+	require.NoError(t, journal.RevertToSnapshot(inner))
+	a, _ := m.Get("a")
+	require.Equal(t, 2, a)
+
+	require.NoError(t, journal.RevertToSnapshot(outer))
+	a, _ = m.Get("a")
+	b, _ := m.Get("b")
+	require.Equal(t, 1, a)
+	require.Equal(t, 0, b)
+
+	// snapshots are consumed by reverting to them or to an earlier snapshot
+	require.ErrorIs(t, journal.RevertToSnapshot(inner), ErrInvalidSnapshot)
+	require.ErrorIs(t, journal.RevertToSnapshot(outer), ErrInvalidSnapshot)
+
+	snapshot := journal.Snapshot()
+	journal.Reset()
+	require.ErrorIs(t, journal.RevertToSnapshot(snapshot), ErrInvalidSnapshot)
+	a, _ = m.Get("a")
+	require.Equal(t, 1, a)
+
+	// discarding the last snapshot commits the changes
+	snapshot = journal.Snapshot()
+	require.NoError(t, m.Set("a", 5))
+	journal.DiscardSnapshot(snapshot)
+	require.Empty(t, journal.entries)
+	a, _ = m.Get("a")
+	require.Equal(t, 5, a)
+}
@@ -0,0 +1,61 @@
+package gosol
+
+import "sync"
+
+// Reader reads the value of a key from an external source, such as a contract on chain
+type Reader[K comparable, V any] interface {
+	Read(key K) (V, error)
+}
+
+// ReaderFunc adapts a function to a Reader
+type ReaderFunc[K comparable, V any] func(key K) (V, error)
+
+func (f ReaderFunc[K, V]) Read(key K) (V, error) {
+	return f(key)
+}
+
+type lazyMapping[K comparable, V any] struct {
+	reader Reader[K, V]
+	mu     sync.RWMutex
+	m      map[K]V
+}
+
+func (m *lazyMapping[K, V]) Get(key K) (value V, err error) {
+	m.mu.RLock()
+	value, ok := m.m[key]
+	m.mu.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	// the reader is called without holding the lock, so concurrent first accesses of a key may both read it,
+	// in which case the first value stored is kept
+	value, err = m.reader.Read(key)
+	if err != nil {
+		return value, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored, ok := m.m[key]; ok {
+		return stored, nil
+	}
+	m.m[key] = value
+	return value, nil
+}
+
+func (m *lazyMapping[K, V]) Set(key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = value
+	return nil
+}
+
+// NewLazyMapping creates a mapping which reads the keys it does not hold yet from reader on first access,
+// and keeps them afterwards. values which are set are never read.
+// it is safe for concurrent use, so that forks can share it.
+func NewLazyMapping[K comparable, V any](reader Reader[K, V]) Mapping[K, V] {
+	return &lazyMapping[K, V]{
+		reader: reader,
+		m:      make(map[K]V),
+	}
+}
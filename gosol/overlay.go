@@ -0,0 +1,157 @@
+package gosol
+
+import "sync"
+
+type overlayMapping[K comparable, V any] struct {
+	// mu guards the overlay, which is written by reads when fork is set
+	mu   sync.RWMutex
+	base Mapping[K, V]
+	fork func(V) V
+	m    map[K]V
+}
+
+func (m *overlayMapping[K, V]) Get(key K) (value V, err error) {
+	m.mu.RLock()
+	value, ok := m.m[key]
+	m.mu.RUnlock()
+	if ok {
+		return value, nil
+	}
+	value, err = m.base.Get(key)
+	if err != nil || m.fork == nil {
+		return value, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// another reader may have forked the value meanwhile
+	if forked, ok := m.m[key]; ok {
+		return forked, nil
+	}
+	value = m.fork(value)
+	m.m[key] = value
+	return value, nil
+}
+
+func (m *overlayMapping[K, V]) Set(key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = value
+	return nil
+}
+
+type overlayEnumerableMapping[K comparable, V any] struct {
+	overlayMapping[K, V]
+	base    EnumerableMapping[K, V]
+	deleted map[K]struct{}
+	// added holds the keys which are not in base, in insertion order
+	added EnumerableMapping[K, struct{}]
+}
+
+func (m *overlayEnumerableMapping[K, V]) isDeleted(key K) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.deleted[key]
+	return ok
+}
+
+func (m *overlayEnumerableMapping[K, V]) Get(key K) (value V, err error) {
+	if m.isDeleted(key) {
+		return value, nil
+	}
+	return m.overlayMapping.Get(key)
+}
+
+func (m *overlayEnumerableMapping[K, V]) Set(key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.deleted, key)
+	if !m.base.Has(key) {
+		if err := m.added.Set(key, struct{}{}); err != nil {
+			return err
+		}
+	}
+	m.m[key] = value
+	return nil
+}
+
+func (m *overlayEnumerableMapping[K, V]) Len() int {
+	return len(m.Keys())
+}
+
+func (m *overlayEnumerableMapping[K, V]) Keys() []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]K, 0, m.base.Len()+m.added.Len())
+	for _, key := range m.base.Keys() {
+		if _, ok := m.deleted[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return append(keys, m.added.Keys()...)
+}
+
+func (m *overlayEnumerableMapping[K, V]) Has(key K) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.deleted[key]; ok {
+		return false
+	}
+	return m.added.Has(key) || m.base.Has(key)
+}
+
+func (m *overlayEnumerableMapping[K, V]) Delete(key K) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.base.Has(key) {
+		m.deleted[key] = struct{}{}
+	}
+	delete(m.m, key)
+	return m.added.Delete(key)
+}
+
+// position returns the position of key among the added keys, the keys of base keep their position when set again
+func (m *overlayEnumerableMapping[K, V]) position(key K) (int, bool) {
+	if m.base.Has(key) {
+		return 0, false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.added.(positionedMapping[K, struct{}]).position(key)
+}
+
+func (m *overlayEnumerableMapping[K, V]) insert(i int, key K, value V) error {
+	if !m.base.Has(key) {
+		m.mu.Lock()
+		err := m.added.(positionedMapping[K, struct{}]).insert(i, key, struct{}{})
+		m.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return m.Set(key, value)
+}
+
+// NewOverlayMapping creates a copy-on-write overlay of base: writes are kept in the overlay, and reads of keys
+// which were not written fall through to base, which is never modified.
+// if fork is not nil, values read from base are passed through it and kept in the overlay, so that mutable values
+// such as nested mappings are not shared with base. the overlay is safe for concurrent reads, so that it can
+// itself be the base of other overlays.
+// the returned mapping is an EnumerableMapping if base is one.
+func NewOverlayMapping[K comparable, V any](base Mapping[K, V], fork func(V) V) Mapping[K, V] {
+	if enumerable, ok := base.(EnumerableMapping[K, V]); ok {
+		overlay := &overlayEnumerableMapping[K, V]{
+			base:    enumerable,
+			deleted: make(map[K]struct{}),
+			added:   NewEnumerableMapping[K, struct{}](),
+		}
+		overlay.overlayMapping.base = base
+		overlay.overlayMapping.fork = fork
+		overlay.overlayMapping.m = make(map[K]V)
+		return overlay
+	}
+	return &overlayMapping[K, V]{
+		base: base,
+		fork: fork,
+		m:    make(map[K]V),
+	}
+}
@@ -1,18 +1,17 @@
 package gosol
 
-import (
-	"errors"
-	"fmt"
-)
-
-var ErrRequireFalse = errors.New("require false")
-
-func Requirer(condition bool, err error) error {
-	if err == nil {
+// Require mirrors require(condition, reason), returning a RevertError if the condition does not hold
+func Require(condition bool, reason string) error {
+	if condition {
 		return nil
 	}
-	if !condition {
-		return fmt.Errorf("%s: %w", ErrRequireFalse, err)
+	return &RevertError{Reason: reason}
+}
+
+// RequireError mirrors require(condition, CustomError()), returning err if the condition does not hold
+func RequireError(condition bool, err error) error {
+	if condition {
+		return nil
 	}
-	return nil
+	return err
 }
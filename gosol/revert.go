@@ -0,0 +1,281 @@
+package gosol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrEmptyRevert is a revert without data, as raised by revert() or a failed call value check
+	ErrEmptyRevert = errors.New("execution reverted")
+	// ErrInvalidRevertData is returned when revert data cannot be decoded
+	ErrInvalidRevertData = errors.New("invalid revert data")
+	// ErrNotRevert is returned when encoding an error which is not a solidity revert
+	ErrNotRevert = errors.New("not a revert")
+)
+
+// selectors of the builtin solidity errors
+var (
+	ErrorSelector = Selector("Error(string)")
+	PanicSelector = Selector("Panic(uint256)")
+)
+
+// panic codes raised by the solidity compiler
+const (
+	PanicGeneric          uint64 = 0x00
+	PanicAssert           uint64 = 0x01
+	PanicArithmetic       uint64 = 0x11
+	PanicDivisionByZero   uint64 = 0x12
+	PanicEnumConversion   uint64 = 0x21
+	PanicStorageEncoding  uint64 = 0x22
+	PanicEmptyArrayPop    uint64 = 0x31
+	PanicArrayOutOfBounds uint64 = 0x32
+	PanicOutOfMemory      uint64 = 0x41
+	PanicInvalidFunction  uint64 = 0x51
+)
+
+var panicDescriptions = map[uint64]string{
+	PanicGeneric:          "generic panic",
+	PanicAssert:           "assertion failed",
+	PanicArithmetic:       "arithmetic underflow or overflow",
+	PanicDivisionByZero:   "division or modulo by zero",
+	PanicEnumConversion:   "invalid enum conversion",
+	PanicStorageEncoding:  "invalid storage byte array encoding",
+	PanicEmptyArrayPop:    "pop on empty array",
+	PanicArrayOutOfBounds: "array index out of bounds",
+	PanicOutOfMemory:      "out of memory",
+	PanicInvalidFunction:  "invalid internal function",
+}
+
+var (
+	stringArguments  = abi.Arguments{{Type: mustNewType("string")}}
+	uint256Arguments = abi.Arguments{{Type: mustNewType("uint256")}}
+)
+
+func mustNewType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// Selector returns the 4-byte selector of a function or error signature
+func Selector(signature string) [4]byte {
+	var selector [4]byte
+	copy(selector[:], crypto.Keccak256([]byte(signature)))
+	return selector
+}
+
+// Revert is an error raised by a solidity revert, with its ABI encoded revert data
+type Revert interface {
+	error
+	RevertData() []byte
+}
+
+type emptyRevert struct {
+	message string
+}
+
+// NewEmptyRevert returns a revert without data, matching ErrEmptyRevert and described by message
+func NewEmptyRevert(message string) error {
+	return &emptyRevert{message: message}
+}
+
+func (e *emptyRevert) Error() string {
+	return e.message
+}
+
+func (e *emptyRevert) Unwrap() error {
+	return ErrEmptyRevert
+}
+
+// RevertError is a revert with a reason string, as raised by require(condition, reason)
+type RevertError struct {
+	Reason string
+}
+
+func (e *RevertError) Error() string {
+	return e.Reason
+}
+
+// Is matches any revert with the same reason
+func (e *RevertError) Is(target error) bool {
+	t, ok := target.(*RevertError)
+	return ok && t.Reason == e.Reason
+}
+
+// RevertData encodes the revert as Error(string)
+func (e *RevertError) RevertData() []byte {
+	args, _ := stringArguments.Pack(e.Reason)
+	return append(ErrorSelector[:], args...)
+}
+
+// PanicError is a revert raised by the compiler checks, as Panic(uint256)
+type PanicError struct {
+	Code uint64
+	// Message optionally overrides the description of the code
+	Message string
+}
+
+// Panic returns the panic raised with the given code
+func Panic(code uint64) error {
+	return &PanicError{Code: code}
+}
+
+func (e *PanicError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if description, ok := panicDescriptions[e.Code]; ok {
+		return fmt.Sprintf("panic: %s (0x%02x)", description, e.Code)
+	}
+	return fmt.Sprintf("panic: unknown code 0x%02x", e.Code)
+}
+
+// Is matches any panic with the same code and message, so that panics sharing a code like overflows and underflows
+// are told apart. a panic without message, like a decoded one, matches the code whatever the message
+func (e *PanicError) Is(target error) bool {
+	t, ok := target.(*PanicError)
+	return ok && t.Code == e.Code && (t.Message == "" || e.Message == "" || t.Message == e.Message)
+}
+
+// RevertData encodes the revert as Panic(uint256)
+func (e *PanicError) RevertData() []byte {
+	args, _ := uint256Arguments.Pack(new(big.Int).SetUint64(e.Code))
+	return append(PanicSelector[:], args...)
+}
+
+// CustomError is a revert raised with a custom error, as in revert InsufficientBalance(available, required)
+type CustomError struct {
+	Selector [4]byte
+	// Signature is empty when the error was decoded from revert data with an unknown selector
+	Signature string
+	// Args are the ABI encoded arguments of the error
+	Args []byte
+}
+
+// NewCustomError encodes the custom error with the given signature and arguments,
+// only elementary and array parameter types are supported
+func NewCustomError(signature string, args ...any) (*CustomError, error) {
+	arguments, err := parseArguments(signature)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := arguments.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomError{
+		Selector:  Selector(signature),
+		Signature: signature,
+		Args:      encoded,
+	}, nil
+}
+
+func (e *CustomError) Error() string {
+	if e.Signature != "" {
+		return e.Signature
+	}
+	return fmt.Sprintf("custom error %s", hexutil.Encode(e.Selector[:]))
+}
+
+// Is matches any custom error with the same selector and arguments,
+// a target without arguments matches the selector whatever the arguments
+func (e *CustomError) Is(target error) bool {
+	t, ok := target.(*CustomError)
+	return ok && t.Selector == e.Selector && (len(t.Args) == 0 || bytes.Equal(t.Args, e.Args))
+}
+
+func (e *CustomError) RevertData() []byte {
+	return append(e.Selector[:], e.Args...)
+}
+
+// Unpack decodes the arguments of the error, following its signature
+func (e *CustomError) Unpack() ([]any, error) {
+	if e.Signature == "" {
+		return nil, fmt.Errorf("%w: unknown signature", ErrInvalidRevertData)
+	}
+	arguments, err := parseArguments(e.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return arguments.Unpack(e.Args)
+}
+
+func parseArguments(signature string) (abi.Arguments, error) {
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return nil, fmt.Errorf("invalid error signature %q", signature)
+	}
+	params := signature[open+1 : len(signature)-1]
+	if params == "" {
+		return abi.Arguments{}, nil
+	}
+	if strings.ContainsAny(params, "() ") {
+		return nil, fmt.Errorf("unsupported error signature %q", signature)
+	}
+	var arguments abi.Arguments
+	for _, param := range strings.Split(params, ",") {
+		typ, err := abi.NewType(param, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, abi.Argument{Type: typ})
+	}
+	return arguments, nil
+}
+
+// EncodeRevert returns the revert data of err, which must wrap a Revert or ErrEmptyRevert
+func EncodeRevert(err error) ([]byte, error) {
+	var revert Revert
+	if errors.As(err, &revert) {
+		return revert.RevertData(), nil
+	}
+	if errors.Is(err, ErrEmptyRevert) {
+		return []byte{}, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrNotRevert, err)
+}
+
+// DecodeRevert returns the revert raised with the given revert data,
+// unknown selectors are returned as a CustomError without signature
+func DecodeRevert(data []byte) (error, error) {
+	if len(data) == 0 {
+		return ErrEmptyRevert, nil
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRevertData, hexutil.Encode(data))
+	}
+	var selector [4]byte
+	copy(selector[:], data[:4])
+	switch selector {
+	case ErrorSelector:
+		values, err := stringArguments.Unpack(data[4:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRevertData, err)
+		}
+		return &RevertError{Reason: values[0].(string)}, nil
+	case PanicSelector:
+		values, err := uint256Arguments.Unpack(data[4:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRevertData, err)
+		}
+		code := values[0].(*big.Int)
+		if !code.IsUint64() {
+			return nil, fmt.Errorf("%w: panic code %s", ErrInvalidRevertData, code)
+		}
+		return &PanicError{Code: code.Uint64()}, nil
+	}
+	return &CustomError{
+		Selector: selector,
+		Args:     bytes.Clone(data[4:]),
+	}, nil
+}
@@ -0,0 +1,126 @@
+package gosol
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequire(t *testing.T) {
+	require.NoError(t, Require(true, "not owner"))
+	err := Require(false, "not owner")
+	require.EqualError(t, err, "not owner")
+
+	// reverts with the same reason are the same error
+	sentinel := &RevertError{Reason: "not owner"}
+	require.ErrorIs(t, err, sentinel)
+	require.ErrorIs(t, fmt.Errorf("supply: %w", err), sentinel)
+	require.NotErrorIs(t, err, &RevertError{Reason: "unauthorized"})
+
+	custom, err := NewCustomError("Unauthorized()")
+	require.NoError(t, err)
+	require.NoError(t, RequireError(true, custom))
+	require.Equal(t, custom, RequireError(false, custom))
+}
+
+func TestRevertData(t *testing.T) {
+	require.Equal(t, "0x08c379a0", hexutil.Encode(ErrorSelector[:]))
+	require.Equal(t, "0x4e487b71", hexutil.Encode(PanicSelector[:]))
+
+	data, err := EncodeRevert(Require(false, "not owner"))
+	require.NoError(t, err)
+	require.Equal(t, "0x08c379a0"+
+		"0000000000000000000000000000000000000000000000000000000000000020"+
+		"0000000000000000000000000000000000000000000000000000000000000009"+
+		"6e6f74206f776e65720000000000000000000000000000000000000000000000", hexutil.Encode(data))
+
+	data, err = EncodeRevert(Panic(PanicArithmetic))
+	require.NoError(t, err)
+	require.Equal(t, "0x4e487b71"+
+		"0000000000000000000000000000000000000000000000000000000000000011", hexutil.Encode(data))
+
+	data, err = EncodeRevert(NewEmptyRevert("non-payable"))
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	_, err = EncodeRevert(errors.New("not a revert"))
+	require.ErrorIs(t, err, ErrNotRevert)
+}
+
+func TestDecodeRevert(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	custom, err := NewCustomError("OwnableUnauthorizedAccount(address)", owner)
+	require.NoError(t, err)
+
+	for _, revert := range []error{
+		Require(false, "not owner"),
+		Panic(PanicDivisionByZero),
+		custom,
+	} {
+		data, err := EncodeRevert(revert)
+		require.NoError(t, err)
+		decoded, err := DecodeRevert(data)
+		require.NoError(t, err)
+		require.ErrorIs(t, decoded, revert)
+	}
+
+	// the message of a panic does not change its revert data
+	overflow := &PanicError{Code: PanicArithmetic, Message: "uint256 overflow"}
+	underflow := &PanicError{Code: PanicArithmetic, Message: "uint256 underflow"}
+	decoded, err := DecodeRevert(overflow.RevertData())
+	require.NoError(t, err)
+	require.EqualError(t, decoded, "panic: arithmetic underflow or overflow (0x11)")
+	// panics with a message are told apart, those without one match the code
+	require.NotErrorIs(t, overflow, underflow)
+	require.ErrorIs(t, decoded, overflow)
+	require.ErrorIs(t, decoded, underflow)
+	require.ErrorIs(t, overflow, Panic(PanicArithmetic))
+	require.NotErrorIs(t, overflow, Panic(PanicDivisionByZero))
+
+	// the signature of a decoded custom error is unknown
+	decoded, err = DecodeRevert(custom.RevertData())
+	require.NoError(t, err)
+	require.EqualError(t, decoded, "custom error 0x118cdaa7")
+	var decodedCustom *CustomError
+	require.True(t, errors.As(decoded, &decodedCustom))
+	decodedCustom.Signature = custom.Signature
+	args, err := decodedCustom.Unpack()
+	require.NoError(t, err)
+	require.Equal(t, []any{owner}, args)
+
+	decoded, err = DecodeRevert(nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, decoded, ErrEmptyRevert)
+
+	_, err = DecodeRevert([]byte{0x08, 0xc3})
+	require.ErrorIs(t, err, ErrInvalidRevertData)
+	_, err = DecodeRevert(ErrorSelector[:])
+	require.ErrorIs(t, err, ErrInvalidRevertData)
+
+	data := append(PanicSelector[:], common.BigToHash(new(big.Int).Lsh(big.NewInt(1), 70)).Bytes()...)
+	_, err = DecodeRevert(data)
+	require.ErrorIs(t, err, ErrInvalidRevertData)
+}
+
+func TestCustomError(t *testing.T) {
+	_, err := NewCustomError("NoParens")
+	require.Error(t, err)
+	_, err = NewCustomError("Tuple((uint256,address))")
+	require.Error(t, err)
+	_, err = NewCustomError("Amount(uint256)", "not a number")
+	require.Error(t, err)
+
+	a, err := NewCustomError("Amount(uint256)", big.NewInt(1))
+	require.NoError(t, err)
+	b, err := NewCustomError("Amount(uint256)", big.NewInt(2))
+	require.NoError(t, err)
+	require.NotErrorIs(t, a, b)
+	require.ErrorIs(t, a, &CustomError{Selector: Selector("Amount(uint256)")})
+	require.NotErrorIs(t, &CustomError{Selector: Selector("Amount(uint256)")}, a)
+	require.EqualError(t, a, "Amount(uint256)")
+}
@@ -0,0 +1,202 @@
+package gosol
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	ErrUint128Overflow = errors.New("uint128 overflow")
+	ErrNotAssignable   = errors.New("mapping is not assignable")
+)
+
+// Storage is the raw word addressed storage of a contract
+type Storage interface {
+	GetState(slot common.Hash) (common.Hash, error)
+	SetState(slot, value common.Hash) error
+}
+
+// MapStorage is an in memory Storage, unset slots are zero
+type MapStorage map[common.Hash]common.Hash
+
+func (s MapStorage) GetState(slot common.Hash) (common.Hash, error) {
+	return s[slot], nil
+}
+
+func (s MapStorage) SetState(slot, value common.Hash) error {
+	s[slot] = value
+	return nil
+}
+
+// StorageCodec converts values to the consecutive storage words they occupy, following the solc storage layout
+type StorageCodec[V any] interface {
+	// Words returns the number of storage words occupied by a value
+	Words() uint64
+	Decode(words []common.Hash) (V, error)
+	Encode(value V) ([]common.Hash, error)
+}
+
+// Slot returns the storage slot of the state variable declared at the given position
+func Slot(n uint64) common.Hash {
+	return common.Hash(uint256.NewInt(n).Bytes32())
+}
+
+// MappingSlot returns the storage slot of the value of a mapping declared at slot, for the abi encoded key:
+// keccak256(key . slot)
+func MappingSlot(key, slot common.Hash) common.Hash {
+	return crypto.Keccak256Hash(key.Bytes(), slot.Bytes())
+}
+
+// SlotOffset returns the storage slot offset words after slot, which is used to address struct fields
+func SlotOffset(slot common.Hash, offset uint64) common.Hash {
+	z := new(uint256.Int).SetBytes32(slot.Bytes())
+	z.Add(z, uint256.NewInt(offset))
+	return common.Hash(z.Bytes32())
+}
+
+// AddressKey abi encodes an address mapping key
+func AddressKey(key common.Address) common.Hash {
+	return common.BytesToHash(key.Bytes())
+}
+
+// HashKey abi encodes a bytes32 mapping key
+func HashKey(key common.Hash) common.Hash {
+	return key
+}
+
+// Uint256Key abi encodes a uint256 mapping key
+func Uint256Key(key uint256.Int) common.Hash {
+	return common.Hash(key.Bytes32())
+}
+
+// PackUint128 packs two uint128 into a word, low taking the lower-order bytes like the first of two packed struct fields
+func PackUint128(low, high *uint256.Int) (common.Hash, error) {
+	if low.BitLen() > 128 || high.BitLen() > 128 {
+		return common.Hash{}, ErrUint128Overflow
+	}
+	z := new(uint256.Int).Lsh(high, 128)
+	z.Or(z, low)
+	return common.Hash(z.Bytes32()), nil
+}
+
+// UnpackUint128 unpacks the two uint128 packed in word by PackUint128
+func UnpackUint128(word common.Hash) (low, high *uint256.Int) {
+	z := new(uint256.Int).SetBytes32(word.Bytes())
+	high = new(uint256.Int).Rsh(z, 128)
+	low = z.And(z, new(uint256.Int).Sub(new(uint256.Int).Lsh(uint256.NewInt(1), 128), uint256.NewInt(1)))
+	return low, high
+}
+
+type storageMapping[K comparable, V any] struct {
+	storage   Storage
+	slot      common.Hash
+	encodeKey func(K) common.Hash
+	codec     StorageCodec[V]
+}
+
+func (m *storageMapping[K, V]) Get(key K) (value V, err error) {
+	location := MappingSlot(m.encodeKey(key), m.slot)
+	words := make([]common.Hash, m.codec.Words())
+	for i := range words {
+		words[i], err = m.storage.GetState(SlotOffset(location, uint64(i)))
+		if err != nil {
+			return value, err
+		}
+	}
+	return m.codec.Decode(words)
+}
+
+func (m *storageMapping[K, V]) Set(key K, value V) error {
+	words, err := m.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	location := MappingSlot(m.encodeKey(key), m.slot)
+	for i, word := range words {
+		if err := m.storage.SetState(SlotOffset(location, uint64(i)), word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewStorageMapping creates a mapping stored in storage like a solidity mapping declared at slot,
+// the value of key being stored from keccak256(encodeKey(key) . slot)
+func NewStorageMapping[K comparable, V any](storage Storage, slot common.Hash, encodeKey func(K) common.Hash, codec StorageCodec[V]) Mapping[K, V] {
+	return &storageMapping[K, V]{
+		storage:   storage,
+		slot:      slot,
+		encodeKey: encodeKey,
+		codec:     codec,
+	}
+}
+
+type nestedStorageMapping[K comparable, V any] struct {
+	slot      common.Hash
+	encodeKey func(K) common.Hash
+	inner     func(slot common.Hash) V
+}
+
+func (m *nestedStorageMapping[K, V]) Get(key K) (value V, err error) {
+	return m.inner(MappingSlot(m.encodeKey(key), m.slot)), nil
+}
+
+func (m *nestedStorageMapping[K, V]) Set(key K, value V) error {
+	return ErrNotAssignable
+}
+
+// NewNestedStorageMapping creates a mapping of mappings declared at slot, inner creating the mapping stored at
+// the slot of a key. like in solidity, the inner mappings always exist and cannot be assigned.
+func NewNestedStorageMapping[K comparable, V any](slot common.Hash, encodeKey func(K) common.Hash, inner func(slot common.Hash) V) Mapping[K, V] {
+	return &nestedStorageMapping[K, V]{
+		slot:      slot,
+		encodeKey: encodeKey,
+		inner:     inner,
+	}
+}
+
+// Uint256Codec stores a uint256 in a word
+type Uint256Codec struct{}
+
+func (Uint256Codec) Words() uint64 { return 1 }
+
+func (Uint256Codec) Decode(words []common.Hash) (uint256.Int, error) {
+	return *new(uint256.Int).SetBytes32(words[0].Bytes()), nil
+}
+
+func (Uint256Codec) Encode(value uint256.Int) ([]common.Hash, error) {
+	return []common.Hash{value.Bytes32()}, nil
+}
+
+// AddressCodec stores an address in the lower-order bytes of a word
+type AddressCodec struct{}
+
+func (AddressCodec) Words() uint64 { return 1 }
+
+func (AddressCodec) Decode(words []common.Hash) (common.Address, error) {
+	return common.BytesToAddress(words[0].Bytes()), nil
+}
+
+func (AddressCodec) Encode(value common.Address) ([]common.Hash, error) {
+	return []common.Hash{AddressKey(value)}, nil
+}
+
+// BoolCodec stores a bool in the lowest-order byte of a word
+type BoolCodec struct{}
+
+func (BoolCodec) Words() uint64 { return 1 }
+
+func (BoolCodec) Decode(words []common.Hash) (bool, error) {
+	return words[0][common.HashLength-1] != 0, nil
+}
+
+func (BoolCodec) Encode(value bool) ([]common.Hash, error) {
+	var word common.Hash
+	if value {
+		word[common.HashLength-1] = 1
+	}
+	return []common.Hash{word}, nil
+}
@@ -0,0 +1,148 @@
+package gosol
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+type rwMutexMapping[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+func (m *rwMutexMapping[K, V]) Get(key K) (value V, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, _ = m.m[key]
+	return value, nil
+}
+
+func (m *rwMutexMapping[K, V]) Set(key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.m[key] = value
+	return nil
+}
+
+// NewRWMutexMapping creates a map backed mapping which is safe for concurrent use
+func NewRWMutexMapping[K comparable, V any]() Mapping[K, V] {
+	return &rwMutexMapping[K, V]{
+		m: make(map[K]V),
+	}
+}
+
+type shardedMapping[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []*rwMutexMapping[K, V]
+}
+
+func (m *shardedMapping[K, V]) shard(key K) *rwMutexMapping[K, V] {
+	return m.shards[hashKey(m.seed, key)%uint64(len(m.shards))]
+}
+
+func (m *shardedMapping[K, V]) Get(key K) (value V, err error) {
+	return m.shard(key).Get(key)
+}
+
+func (m *shardedMapping[K, V]) Set(key K, value V) error {
+	return m.shard(key).Set(key, value)
+}
+
+// NewShardedMapping creates a mapping which is safe for concurrent use, spreading its keys over the given number
+// of independently locked maps to reduce lock contention
+func NewShardedMapping[K comparable, V any](shards int) Mapping[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+	m := &shardedMapping[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*rwMutexMapping[K, V], shards),
+	}
+	for i := range m.shards {
+		m.shards[i] = &rwMutexMapping[K, V]{m: make(map[K]V)}
+	}
+	return m
+}
+
+// hashKey hashes the key of a sharded mapping, using the bytes of the key if it exposes them
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case common.Hash:
+		return maphash.Bytes(seed, k[:])
+	case common.Address:
+		return maphash.Bytes(seed, k[:])
+	case uint256.Int:
+		b := k.Bytes32()
+		return maphash.Bytes(seed, b[:])
+	case string:
+		return maphash.String(seed, k)
+	case interface{ Bytes() []byte }:
+		return maphash.Bytes(seed, k.Bytes())
+	default:
+		return maphash.String(seed, fmt.Sprint(key))
+	}
+}
+
+type rwMutexEnumerableMapping[K comparable, V any] struct {
+	mu    sync.RWMutex
+	inner EnumerableMapping[K, V]
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) Get(key K) (value V, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inner.Get(key)
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) Set(key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inner.Set(key, value)
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inner.Len()
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) Keys() []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inner.Keys()
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) Has(key K) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inner.Has(key)
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) Delete(key K) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inner.Delete(key)
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) position(key K) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.inner.(positionedMapping[K, V]).position(key)
+}
+
+func (m *rwMutexEnumerableMapping[K, V]) insert(i int, key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inner.(positionedMapping[K, V]).insert(i, key, value)
+}
+
+// NewRWMutexEnumerableMapping creates a map backed EnumerableMapping which is safe for concurrent use
+func NewRWMutexEnumerableMapping[K comparable, V any]() EnumerableMapping[K, V] {
+	return &rwMutexEnumerableMapping[K, V]{
+		inner: NewEnumerableMapping[K, V](),
+	}
+}
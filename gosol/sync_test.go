@@ -0,0 +1,102 @@
+package gosol
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentMappings(t *testing.T) {
+	mappings := map[string]Mapping[common.Hash, uint256.Int]{
+		"rwmutex":    NewRWMutexMapping[common.Hash, uint256.Int](),
+		"sharded":    NewShardedMapping[common.Hash, uint256.Int](8),
+		"enumerable": NewRWMutexEnumerableMapping[common.Hash, uint256.Int](),
+		"lazy": NewLazyMapping(ReaderFunc[common.Hash, uint256.Int](func(key common.Hash) (uint256.Int, error) {
+			return *new(uint256.Int).SetBytes32(key.Bytes()), nil
+		})),
+	}
+	for name, m := range mappings {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 1000; i++ {
+						key := uint64(g*1000 + i + 1)
+						_ = m.Set(common.BytesToHash(uint256.NewInt(key).Bytes()), *uint256.NewInt(key))
+						_, _ = m.Get(common.Hash{})
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			for key := uint64(1); key <= 8000; key++ {
+				value, err := m.Get(common.BytesToHash(uint256.NewInt(key).Bytes()))
+				require.NoError(t, err)
+				require.Equal(t, key, value.Uint64())
+			}
+		})
+	}
+}
+
+func TestMappingFactory(t *testing.T) {
+	require.IsType(t, &mapMapping[string, int]{}, MakeMapping[string, int](MappingFactory{}))
+	require.IsType(t, &rwMutexMapping[string, int]{}, MakeMapping[string, int](MappingFactory{Kind: RWMutexKind}))
+	sharded := MakeMapping[string, int](MappingFactory{Kind: ShardedKind})
+	require.Len(t, sharded.(*shardedMapping[string, int]).shards, DefaultShards)
+	require.IsType(t, &rwMutexEnumerableMapping[string, int]{}, MakeEnumerableMapping[string, int](MappingFactory{Kind: ShardedKind}))
+}
+
+func benchmarkKeys(n int) []common.Hash {
+	keys := make([]common.Hash, n)
+	for i := range keys {
+		keys[i] = common.BytesToHash(uint256.NewInt(uint64(i)).Bytes())
+	}
+	return keys
+}
+
+func BenchmarkMappings(b *testing.B) {
+	keys := benchmarkKeys(4096)
+	factories := []MappingFactory{{Kind: MapKind}, {Kind: RWMutexKind}, {Kind: ShardedKind}}
+	names := []string{"map", "rwmutex", "sharded"}
+	for i, factory := range factories {
+		m := MakeMapping[common.Hash, uint256.Int](factory)
+		for j, key := range keys {
+			_ = m.Set(key, *uint256.NewInt(uint64(j)))
+		}
+
+		b.Run(fmt.Sprintf("%s/get", names[i]), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_, _ = m.Get(keys[n%len(keys)])
+			}
+		})
+		b.Run(fmt.Sprintf("%s/set", names[i]), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_ = m.Set(keys[n%len(keys)], *uint256.NewInt(uint64(n)))
+			}
+		})
+		if factory.Kind == MapKind {
+			// plain maps do not support concurrent writes
+			continue
+		}
+		b.Run(fmt.Sprintf("%s/parallel", names[i]), func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				n := 0
+				for pb.Next() {
+					key := keys[n%len(keys)]
+					if n%4 == 0 {
+						_ = m.Set(key, *uint256.NewInt(uint64(n)))
+					} else {
+						_, _ = m.Get(key)
+					}
+					n++
+				}
+			})
+		})
+	}
+}
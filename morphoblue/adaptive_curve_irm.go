@@ -0,0 +1,87 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+)
+
+// AdaptiveCurveIrm is a stateful IRM which keeps the rate at target of every market,
+// wrapping AdaptiveIRM.GetBorrowRate.
+// reference implementation:
+// https://github.com/morpho-org/morpho-blue-irm/blob/main/src/adaptive-curve-irm/AdaptiveCurveIrm.sol
+type AdaptiveCurveIrm struct {
+	RateAtTarget gosol.Mapping[common.Hash, uint256.Int]
+
+	// BlockTimestamp returns the simulated block.timestamp, used to compute the time elapsed since the last market update
+	BlockTimestamp func() uint64
+}
+
+// NewAdaptiveCurveIrm creates a new AdaptiveCurveIrm for morpho, reading its block timestamp.
+// the rates at target are backed like the mappings of morpho and journaled by it, so that they are restored
+// when an operation reverts
+func NewAdaptiveCurveIrm(morpho *Morpho) *AdaptiveCurveIrm {
+	return &AdaptiveCurveIrm{
+		RateAtTarget: gosol.NewJournaledMapping(morpho.Journal, gosol.MakeMapping[common.Hash, uint256.Int](morpho.factory)),
+		BlockTimestamp: func() uint64 {
+			return morpho.BlockTimestamp
+		},
+	}
+}
+
+// BorrowRate returns the average borrow rate of the market since its last update, and stores its new rate at target
+func (irm *AdaptiveCurveIrm) BorrowRate(marketParams MarketParams, market Market) (*uint256.Int, error) {
+	id := ComputeMarketId(marketParams)
+	avgRate, endRateAtTarget, err := irm.borrowRate(id, market)
+	if err != nil {
+		return nil, err
+	}
+	if err := irm.RateAtTarget.Set(id, *endRateAtTarget); err != nil {
+		return nil, err
+	}
+	return avgRate, nil
+}
+
+// BorrowRateView returns the average borrow rate of the market since its last update, without modifying any state
+func (irm *AdaptiveCurveIrm) BorrowRateView(marketParams MarketParams, market Market) (*uint256.Int, error) {
+	avgRate, _, err := irm.borrowRate(ComputeMarketId(marketParams), market)
+	return avgRate, err
+}
+
+func (irm *AdaptiveCurveIrm) borrowRate(id common.Hash, market Market) (*uint256.Int, *uint256.Int, error) {
+	startRateAtTarget, err := irm.RateAtTarget.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return AdaptiveCurveBorrowRate(market, &startRateAtTarget, irm.BlockTimestamp())
+}
+
+// AdaptiveCurveBorrowRate returns the average borrow rate of the market since its last update and its new rate at target,
+// like AdaptiveCurveIrm called at timestamp with the given rate at target, zero if the market is not initialized
+func AdaptiveCurveBorrowRate(market Market, startRateAtTarget *uint256.Int, timestamp uint64) (*uint256.Int, *uint256.Int, error) {
+	utilization := new(uint256.Int)
+	if !market.TotalSupplyAssets.IsZero() {
+		if _, err := WadDivDown(utilization, &market.TotalBorrowAssets, &market.TotalSupplyAssets); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	elapsed := new(uint256.Int)
+	if !startRateAtTarget.IsZero() {
+		if _, underflow := elapsed.SubOverflow(uint256.NewInt(timestamp), &market.LastUpdate); underflow {
+			return nil, nil, ErrorUint256Underflow
+		}
+	}
+
+	return AdaptiveIRM.GetBorrowRate(utilization, startRateAtTarget, elapsed)
+}
+
+// Fork returns a copy-on-write overlay of the IRM, reading the block timestamp of the given fork of Morpho
+func (irm *AdaptiveCurveIrm) Fork(morpho *Morpho) IRM {
+	return &AdaptiveCurveIrm{
+		RateAtTarget: forkMapping(morpho.Journal, irm.RateAtTarget, nil),
+		BlockTimestamp: func() uint64 {
+			return morpho.BlockTimestamp
+		},
+	}
+}
@@ -1,177 +1,269 @@
 package morphoblue
 
-import "github.com/holiman/uint256"
+import (
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+)
 
 type adaptiveIRM struct {
 	// constants
 
-	CURVE_STEEPNESS    *uint256.Int
-	TARGET_UTILIZATION *uint256.Int
+	CURVE_STEEPNESS    gosol.Int256
+	TARGET_UTILIZATION gosol.Int256
 
-	INITIAL_RATE_AT_TARGET *uint256.Int
-	ADJUSTMENT_SPEED       *uint256.Int
-	MIN_RATE_AT_TARGET     *uint256.Int
-	MAX_RATE_AT_TARGET     *uint256.Int
+	INITIAL_RATE_AT_TARGET gosol.Int256
+	ADJUSTMENT_SPEED       gosol.Int256
+	MIN_RATE_AT_TARGET     gosol.Int256
+	MAX_RATE_AT_TARGET     gosol.Int256
 
-	LN_2_INT         *uint256.Int
-	LN_WEI_INT       *uint256.Int
-	WEXP_UPPER_BOUND *uint256.Int
-	WEXP_UPPER_VALUE *uint256.Int
+	LN_2_INT         gosol.Int256
+	LN_WEI_INT       gosol.Int256
+	WEXP_UPPER_BOUND gosol.Int256
+	WEXP_UPPER_VALUE gosol.Int256
 }
 
 var AdaptiveIRM = &adaptiveIRM{
-	CURVE_STEEPNESS:    uint256.MustFromDecimal("4000000000000000000"),
-	TARGET_UTILIZATION: uint256.MustFromDecimal("900000000000000000"),
-
-	INITIAL_RATE_AT_TARGET: new(uint256.Int).Div(uint256.MustFromDecimal("4000000000000000"), SECONDS_PER_YEAR),
-	ADJUSTMENT_SPEED:       new(uint256.Int).Div(uint256.MustFromDecimal("50000000000000000000"), SECONDS_PER_YEAR),
-	MIN_RATE_AT_TARGET:     new(uint256.Int).Div(uint256.MustFromDecimal("1000000000000000"), SECONDS_PER_YEAR),
-	MAX_RATE_AT_TARGET:     new(uint256.Int).Div(uint256.MustFromDecimal("2000000000000000000"), SECONDS_PER_YEAR),
-
-	LN_2_INT:         uint256.MustFromDecimal("693147180559945309"),
-	LN_WEI_INT:       (&uint256.Int{}).Neg(uint256.MustFromDecimal("41446531673892822312")),
-	WEXP_UPPER_BOUND: uint256.MustFromDecimal("93859467695000404319"),
-	WEXP_UPPER_VALUE: uint256.MustFromDecimal("57716089161558943949701069502944508345128422502756744429568"),
+	CURVE_STEEPNESS:    gosol.MustInt256FromDecimal("4000000000000000000"),
+	TARGET_UTILIZATION: gosol.MustInt256FromDecimal("900000000000000000"),
+
+	INITIAL_RATE_AT_TARGET: perSecond("4000000000000000"),
+	ADJUSTMENT_SPEED:       perSecond("50000000000000000000"),
+	MIN_RATE_AT_TARGET:     perSecond("1000000000000000"),
+	MAX_RATE_AT_TARGET:     perSecond("2000000000000000000"),
+
+	LN_2_INT:         gosol.MustInt256FromDecimal("693147180559945309"),
+	LN_WEI_INT:       gosol.MustInt256FromDecimal("-41446531673892822312"),
+	WEXP_UPPER_BOUND: gosol.MustInt256FromDecimal("93859467695000404319"),
+	WEXP_UPPER_VALUE: gosol.MustInt256FromDecimal("57716089161558943949701069502944508345128422502756744429568"),
+}
+
+// perSecond converts a yearly rate to a per second rate
+func perSecond(yearly string) gosol.Int256 {
+	return gosol.Int256FromBits(new(uint256.Int).Div(uint256.MustFromDecimal(yearly), SECONDS_PER_YEAR))
 }
 
+// ExpLibWExp returns an approximation of exp(x), reading and returning int256 in two's complement.
+// x is bounded before any arithmetic, so it cannot overflow
 func (irm *adaptiveIRM) ExpLibWExp(x *uint256.Int) *uint256.Int {
-	if x.Slt(irm.LN_WEI_INT) {
-		return uint256.NewInt(0)
+	z, _ := irm.WExp(gosol.Int256FromBits(x))
+	return z.Bits()
+}
+
+// WExp returns an approximation of exp(x)
+// https://github.com/morpho-org/morpho-blue-irm/blob/main/src/adaptive-curve-irm/libraries/ExpLib.sol
+func (irm *adaptiveIRM) WExp(x gosol.Int256) (gosol.Int256, error) {
+	// Return zero if x < ln(1e-18)
+	if x.Lt(irm.LN_WEI_INT) {
+		return gosol.NewInt256(0), nil
 	}
-	if !x.Slt(irm.WEXP_UPPER_BOUND) {
-		return new(uint256.Int).Set(irm.WEXP_UPPER_VALUE)
+	// Clip to avoid overflowing
+	if !x.Lt(irm.WEXP_UPPER_BOUND) {
+		return irm.WEXP_UPPER_VALUE, nil
 	}
 
 	// roundingAdjustment = (x < 0) ? -(LN_2_INT / 2) : (LN_2_INT / 2);
-	roundingAdj := new(uint256.Int).SDiv(irm.LN_2_INT, uint256.NewInt(2))
+	roundingAdjustment, err := irm.LN_2_INT.Div(gosol.NewInt256(2))
+	if err != nil {
+		return gosol.Int256{}, err
+	}
 	if x.Sign() < 0 {
-		roundingAdj.Neg(roundingAdj)
+		if roundingAdjustment, err = roundingAdjustment.Neg(); err != nil {
+			return gosol.Int256{}, err
+		}
 	}
 
-	// q = (x + roundingAdjustment) / LN_2_INT;
-	q := new(uint256.Int).Add(x, roundingAdj)
-	q.SDiv(q, irm.LN_2_INT)
+	// int256 q = (x + roundingAdjustment) / LN_2_INT;
+	q, err := x.Add(roundingAdjustment)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	if q, err = q.Div(irm.LN_2_INT); err != nil {
+		return gosol.Int256{}, err
+	}
 
 	// int256 r = x - q * LN_2_INT;
-	r := new(uint256.Int).Mul(q, irm.LN_2_INT)
-	r.Sub(x, r)
-
-	rSquared := new(uint256.Int).Mul(r, r)
+	r, err := q.Mul(irm.LN_2_INT)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	if r, err = x.Sub(r); err != nil {
+		return gosol.Int256{}, err
+	}
 
-	expR := new(uint256.Int).SDiv(rSquared, WAD)
-	expR.SDiv(expR, uint256.NewInt(2))
-	expR.Add(expR, r)
-	expR.Add(expR, WAD)
+	// uint256 expR = uint256(WAD_INT + r + (r * r) / WAD_INT / 2);
+	rSquared, err := r.Mul(r)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	if rSquared, err = rSquared.Div(gosol.WadInt256); err != nil {
+		return gosol.Int256{}, err
+	}
+	if rSquared, err = rSquared.Div(gosol.NewInt256(2)); err != nil {
+		return gosol.Int256{}, err
+	}
+	expR, err := gosol.WadInt256.Add(r)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	if expR, err = expR.Add(rSquared); err != nil {
+		return gosol.Int256{}, err
+	}
 
-	if q.Sign() == 0 {
-		expR.Lsh(expR, uint(q.Uint64()))
-		return expR
+	// Return e^x = 2^q * e^r, the shifts are unchecked
+	if q.Sign() >= 0 {
+		return gosol.Int256FromBits(new(uint256.Int).Lsh(expR.Bits(), uint(q.Bits().Uint64()))), nil
+	}
+	negQ, err := q.Neg()
+	if err != nil {
+		return gosol.Int256{}, err
 	}
-	q.Neg(q)
-	expR.Rsh(expR, uint(q.Uint64()))
-	return expR
+	return gosol.Int256FromBits(new(uint256.Int).Rsh(expR.Bits(), uint(negQ.Bits().Uint64()))), nil
 }
 
 // simplified, since we only need the start rate, elapsed time, and utilization. we dont need the market id or the other market parameters
 // https://github.com/morpho-org/morpho-blue-irm/blob/main/src/adaptive-curve-irm/AdaptiveCurveIrm.sol#L76C47-L76C53
-func (irm *adaptiveIRM) GetBorrowRate(utilization, startRateAtTarget, elapsed *uint256.Int) (*uint256.Int, *uint256.Int) {
+func (irm *adaptiveIRM) GetBorrowRate(utilization, startRateAtTarget, elapsed *uint256.Int) (*uint256.Int, *uint256.Int, error) {
+	utilizationInt, err := gosol.ToInt256(utilization)
+	if err != nil {
+		return nil, nil, err
+	}
+	startRateAtTargetInt, err := gosol.ToInt256(startRateAtTarget)
+	if err != nil {
+		return nil, nil, err
+	}
+	elapsedInt, err := gosol.ToInt256(elapsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	//   int256 errNormFactor = utilization > ConstantsLib.TARGET_UTILIZATION
 	//        ? WAD - ConstantsLib.TARGET_UTILIZATION
 	//        : ConstantsLib.TARGET_UTILIZATION;
-	errNormFactor := new(uint256.Int)
-	if utilization.Sgt(irm.TARGET_UTILIZATION) {
-		errNormFactor.Sub(WAD, irm.TARGET_UTILIZATION)
-	} else {
-		errNormFactor.Set(irm.TARGET_UTILIZATION)
+	errNormFactor := irm.TARGET_UTILIZATION
+	if utilizationInt.Gt(irm.TARGET_UTILIZATION) {
+		if errNormFactor, err = gosol.WadInt256.Sub(irm.TARGET_UTILIZATION); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	//  int256 err = (utilization - ConstantsLib.TARGET_UTILIZATION).wDivToZero(errNormFactor);
-	ERR := new(uint256.Int).Sub(utilization, irm.TARGET_UTILIZATION)
-	ERR = WadDivToZero(ERR, ERR, errNormFactor)
-
-	avgRateAtTarget := new(uint256.Int)
-	endRateAtTarget := new(uint256.Int)
-	if startRateAtTarget.IsZero() {
-		avgRateAtTarget.Set(irm.INITIAL_RATE_AT_TARGET)
-		endRateAtTarget.Set(irm.INITIAL_RATE_AT_TARGET)
+	ERR, err := utilizationInt.Sub(irm.TARGET_UTILIZATION)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ERR, err = gosol.WDivToZero(ERR, errNormFactor); err != nil {
+		return nil, nil, err
+	}
+
+	var avgRateAtTarget, endRateAtTarget gosol.Int256
+	if startRateAtTargetInt.IsZero() {
+		avgRateAtTarget = irm.INITIAL_RATE_AT_TARGET
+		endRateAtTarget = irm.INITIAL_RATE_AT_TARGET
 	} else {
 		// int256 speed = ConstantsLib.ADJUSTMENT_SPEED.wMulToZero(err);
-		speed := WadMulToZero(
-			new(uint256.Int),
-			irm.ADJUSTMENT_SPEED,
-			ERR,
-		)
+		speed, err := gosol.WMulToZero(irm.ADJUSTMENT_SPEED, ERR)
+		if err != nil {
+			return nil, nil, err
+		}
 
 		// elapsed is seconds from now to the last market update.
-		linearAdaptation := new(uint256.Int).Mul(speed, elapsed)
+		linearAdaptation, err := speed.Mul(elapsedInt)
+		if err != nil {
+			return nil, nil, err
+		}
 
 		if linearAdaptation.IsZero() {
-			avgRateAtTarget.Set(startRateAtTarget)
-			endRateAtTarget.Set(startRateAtTarget)
+			avgRateAtTarget = startRateAtTargetInt
+			endRateAtTarget = startRateAtTargetInt
 		} else {
-
-			// https://github.com/morpho-org/morpho-blue-irm/blob/main/src/adaptive-curve-irm/AdaptiveCurveIrm.sol#L109
-
-			// function _newRateAtTarget(int256 startRateAtTarget, int256 linearAdaptation) private pure returns (int256) {
-			//     // Non negative because MIN_RATE_AT_TARGET > 0.
-			//     return startRateAtTarget.wMulToZero(ExpLib.wExp(linearAdaptation)).bound(
-			//         ConstantsLib.MIN_RATE_AT_TARGET, ConstantsLib.MAX_RATE_AT_TARGET
-			//     );
-			// }
-			_newRateAtTarget := func(srat, adaptation *uint256.Int) *uint256.Int {
-				expResult := irm.ExpLibWExp(adaptation)
-				// WMulToZero ->  return (x * y) / WAD_INT;
-				result := WadMulToZero(new(uint256.Int), srat, expResult)
-				// bound between min and max rate. make sure to use signed operations
-				if result.Slt(irm.MIN_RATE_AT_TARGET) {
-					return new(uint256.Int).Set(irm.MIN_RATE_AT_TARGET)
-				}
-				if result.Sgt(irm.MAX_RATE_AT_TARGET) {
-					return new(uint256.Int).Set(irm.MAX_RATE_AT_TARGET)
-				}
-				return result
-			}
 			// endRateAtTarget = _newRateAtTarget(startRateAtTarget, linearAdaptation);
-			endRateAtTarget = _newRateAtTarget(startRateAtTarget, linearAdaptation)
+			if endRateAtTarget, err = irm.newRateAtTarget(startRateAtTargetInt, linearAdaptation); err != nil {
+				return nil, nil, err
+			}
 
 			// int256 midRateAtTarget = _newRateAtTarget(startRateAtTarget, linearAdaptation / 2);
-			halfAdaptation := new(uint256.Int).SDiv(linearAdaptation, uint256.NewInt(2))
-			midRate := _newRateAtTarget(startRateAtTarget, halfAdaptation)
-			midRate.Mul(midRate, uint256.NewInt(2))
+			halfAdaptation, err := linearAdaptation.Div(gosol.NewInt256(2))
+			if err != nil {
+				return nil, nil, err
+			}
+			midRateAtTarget, err := irm.newRateAtTarget(startRateAtTargetInt, halfAdaptation)
+			if err != nil {
+				return nil, nil, err
+			}
 
 			// avgRateAtTarget = (startRateAtTarget + endRateAtTarget + 2 * midRateAtTarget) / 4;
-			avgRateAtTarget.Add(startRateAtTarget, endRateAtTarget)
-			avgRateAtTarget.Add(avgRateAtTarget, midRate)
-			avgRateAtTarget.SDiv(avgRateAtTarget, uint256.NewInt(4))
+			if midRateAtTarget, err = midRateAtTarget.Mul(gosol.NewInt256(2)); err != nil {
+				return nil, nil, err
+			}
+			if avgRateAtTarget, err = startRateAtTargetInt.Add(endRateAtTarget); err != nil {
+				return nil, nil, err
+			}
+			if avgRateAtTarget, err = avgRateAtTarget.Add(midRateAtTarget); err != nil {
+				return nil, nil, err
+			}
+			if avgRateAtTarget, err = avgRateAtTarget.Div(gosol.NewInt256(4)); err != nil {
+				return nil, nil, err
+			}
 		}
 	}
+
 	//  return (uint256(_curve(avgRateAtTarget, err)), endRateAtTarget);
+	borrowRate, err := irm.curve(avgRateAtTarget, ERR)
+	if err != nil {
+		return nil, nil, err
+	}
+	borrowRateUint, err := borrowRate.ToUint256()
+	if err != nil {
+		return nil, nil, err
+	}
+	endRateAtTargetUint, err := endRateAtTarget.ToUint256()
+	if err != nil {
+		return nil, nil, err
+	}
+	return borrowRateUint, endRateAtTargetUint, nil
+}
 
-	//function _curve(int256 _rateAtTarget, int256 err) private pure returns (int256) {
-	//    // Non negative because 1 - 1/C >= 0, C - 1 >= 0.
-	//    // Non negative if _rateAtTarget >= 0 because if err < 0, coeff <= 1.
-	//}
+// https://github.com/morpho-org/morpho-blue-irm/blob/main/src/adaptive-curve-irm/AdaptiveCurveIrm.sol#L109
+func (irm *adaptiveIRM) newRateAtTarget(startRateAtTarget, linearAdaptation gosol.Int256) (gosol.Int256, error) {
+	// Non negative because MIN_RATE_AT_TARGET > 0.
+	// return startRateAtTarget.wMulToZero(ExpLib.wExp(linearAdaptation)).bound(MIN_RATE_AT_TARGET, MAX_RATE_AT_TARGET);
+	exp, err := irm.WExp(linearAdaptation)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	rate, err := gosol.WMulToZero(startRateAtTarget, exp)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	return gosol.Bound(rate, irm.MIN_RATE_AT_TARGET, irm.MAX_RATE_AT_TARGET), nil
+}
 
-	//    int256 coeff = err < 0 ? WAD - WAD.wDivToZero(ConstantsLib.CURVE_STEEPNESS) : ConstantsLib.CURVE_STEEPNESS - WAD;
-	coeff := new(uint256.Int)
+// https://github.com/morpho-org/morpho-blue-irm/blob/main/src/adaptive-curve-irm/AdaptiveCurveIrm.sol#L124
+func (irm *adaptiveIRM) curve(rateAtTarget, ERR gosol.Int256) (gosol.Int256, error) {
+	// Non negative because 1 - 1/C >= 0, C - 1 >= 0.
+	// int256 coeff = err < 0 ? WAD - WAD.wDivToZero(ConstantsLib.CURVE_STEEPNESS) : ConstantsLib.CURVE_STEEPNESS - WAD;
+	var coeff gosol.Int256
+	var err error
 	if ERR.Sign() < 0 {
-		// TODO: this is a constant that we can precomputer, right?
-		wadDiv := WadDivToZero(
-			new(uint256.Int), WAD, irm.CURVE_STEEPNESS,
-		)
-		coeff.Sub(WAD, wadDiv)
+		if coeff, err = gosol.WDivToZero(gosol.WadInt256, irm.CURVE_STEEPNESS); err != nil {
+			return gosol.Int256{}, err
+		}
+		coeff, err = gosol.WadInt256.Sub(coeff)
 	} else {
-		coeff.Sub(irm.CURVE_STEEPNESS, WAD)
+		coeff, err = irm.CURVE_STEEPNESS.Sub(gosol.WadInt256)
+	}
+	if err != nil {
+		return gosol.Int256{}, err
 	}
 
+	// Non negative if _rateAtTarget >= 0 because if err < 0, coeff <= 1.
 	// return (coeff.wMulToZero(err) + WAD).wMulToZero(int256(_rateAtTarget));
-	borrowRate := WadMulToZero(
-		new(uint256.Int),
-		coeff,
-		ERR,
-	)
-	borrowRate.Add(borrowRate, WAD)
-	borrowRate = WadMulToZero(borrowRate, borrowRate, avgRateAtTarget)
-
-	return borrowRate, endRateAtTarget
+	rate, err := gosol.WMulToZero(coeff, ERR)
+	if err != nil {
+		return gosol.Int256{}, err
+	}
+	if rate, err = rate.Add(gosol.WadInt256); err != nil {
+		return gosol.Int256{}, err
+	}
+	return gosol.WMulToZero(rate, rateAtTarget)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 )
@@ -22,6 +23,10 @@ func TestExpLib(t *testing.T) {
 			In:  uint256.NewInt(0).Neg(uint256.MustFromDecimal("1907753029319520")),
 			Out: uint256.NewInt(998094066731490918),
 		},
+		{
+			In:  uint256.MustFromDecimal("1000000000000000000"),
+			Out: uint256.NewInt(2707864291678420188),
+		},
 	}
 
 	for idx, tc := range testCases {
@@ -53,13 +58,75 @@ func TestAdaptiveCurve(t *testing.T) {
 
 	for idx, tc := range testCases {
 		t.Run(fmt.Sprintf("Case%d", idx), func(t *testing.T) {
-			borrowRate, endRate := AdaptiveIRM.GetBorrowRate(
+			borrowRate, endRate, err := AdaptiveIRM.GetBorrowRate(
 				tc.Utilization,
 				tc.StartRate,
 				tc.Duration,
 			)
+			require.NoError(t, err)
 			require.Equal(t, tc.BorrowRate.String(), borrowRate.String())
 			require.Equal(t, tc.EndRate.String(), endRate.String())
 		})
 	}
 }
+
+func TestAdaptiveCurveIrm(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	morpho := NewMorpho(owner, owner, WithBlockTimestamp(1700000000))
+	now := morpho.BlockTimestamp
+	irm := NewAdaptiveCurveIrm(morpho)
+
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *uint256.MustFromDecimal("800000000000000000"),
+	}
+	marketId := ComputeMarketId(marketParams)
+	market := Market{
+		TotalSupplyAssets: *uint256.MustFromDecimal("1000000000000000000"),
+		TotalBorrowAssets: *uint256.NewInt(559455285663861780),
+		LastUpdate:        *uint256.NewInt(now - 4490),
+	}
+
+	// the first call initializes the rate at target
+	_, err := irm.BorrowRate(marketParams, market)
+	require.NoError(t, err)
+	rateAtTarget, _ := irm.RateAtTarget.Get(marketId)
+	require.Equal(t, AdaptiveIRM.INITIAL_RATE_AT_TARGET.String(), rateAtTarget.String())
+
+	irm.RateAtTarget.Set(marketId, *uint256.NewInt(240238572))
+
+	// the view does not update the rate at target
+	borrowRate, err := irm.BorrowRateView(marketParams, market)
+	require.NoError(t, err)
+	require.Equal(t, "171830421", borrowRate.String())
+	rateAtTarget, _ = irm.RateAtTarget.Get(marketId)
+	require.Equal(t, "240238572", rateAtTarget.String())
+
+	borrowRate, err = irm.BorrowRate(marketParams, market)
+	require.NoError(t, err)
+	require.Equal(t, "171830421", borrowRate.String())
+	rateAtTarget, _ = irm.RateAtTarget.Get(marketId)
+	require.Equal(t, "239592324", rateAtTarget.String())
+
+	t.Run("Reverted operations do not update the rate at target", func(t *testing.T) {
+		supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+		require.NoError(t, morpho.Irms.Set(marketParams.Irm, irm))
+		require.NoError(t, morpho.EnableIrm(owner, marketParams.Irm))
+		require.NoError(t, morpho.EnableLltv(owner, &marketParams.Lltv))
+		require.NoError(t, morpho.CreateMarket(owner, marketParams))
+		require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.NewInt(1000)))
+		_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.NewInt(1000), uint256.NewInt(0), supplier, nil)
+		require.NoError(t, err)
+		before, _ := irm.RateAtTarget.Get(marketId)
+
+		// the interest is accrued before the transfer of the loan token, which fails without a balance
+		morpho.BlockTimestamp += 86400
+		_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.NewInt(1000), uint256.NewInt(0), supplier, nil)
+		require.Error(t, err)
+		after, _ := irm.RateAtTarget.Get(marketId)
+		require.Equal(t, before.String(), after.String())
+	})
+}
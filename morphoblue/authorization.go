@@ -0,0 +1,80 @@
+package morphoblue
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// EIP-712 type hashes based on ConstantsLib.sol
+var (
+	DOMAIN_TYPEHASH        = crypto.Keccak256Hash([]byte("EIP712Domain(uint256 chainId,address verifyingContract)"))
+	AUTHORIZATION_TYPEHASH = crypto.Keccak256Hash([]byte("Authorization(address authorizer,address authorized,bool isAuthorized,uint256 nonce,uint256 deadline)"))
+)
+
+// DomainSeparator computes keccak256(abi.encode(DOMAIN_TYPEHASH, chainId, morpho))
+func DomainSeparator(chainId uint64, morpho common.Address) common.Hash {
+	data := make([]byte, 3*32)
+	copy(data[0:32], DOMAIN_TYPEHASH.Bytes())
+	chainIdBytes := uint256.NewInt(chainId).Bytes32()
+	copy(data[32:64], chainIdBytes[:])
+	copy(data[76:96], morpho.Bytes())
+	return crypto.Keccak256Hash(data)
+}
+
+// AuthorizationDigest computes the EIP-712 digest of the authorization:
+// keccak256("\x19\x01" . domainSeparator . keccak256(abi.encode(AUTHORIZATION_TYPEHASH, authorization)))
+func AuthorizationDigest(domainSeparator common.Hash, authorization Authorization) common.Hash {
+	data := make([]byte, 6*32)
+	copy(data[0:32], AUTHORIZATION_TYPEHASH.Bytes())
+	copy(data[44:64], authorization.Authorizer.Bytes())
+	copy(data[76:96], authorization.Authorized.Bytes())
+	if authorization.IsAuthorized {
+		data[127] = 1
+	}
+	nonceBytes := authorization.Nonce.Bytes32()
+	copy(data[128:160], nonceBytes[:])
+	deadlineBytes := authorization.Deadline.Bytes32()
+	copy(data[160:192], deadlineBytes[:])
+	hashStruct := crypto.Keccak256Hash(data)
+
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator.Bytes(), hashStruct.Bytes())
+}
+
+// Ecrecover mirrors the ecrecover precompile, returning the zero address if the signature is invalid
+func Ecrecover(digest common.Hash, signature Signature) common.Address {
+	if signature.V != 27 && signature.V != 28 {
+		return common.Address{}
+	}
+	r := new(big.Int).SetBytes(signature.R.Bytes())
+	s := new(big.Int).SetBytes(signature.S.Bytes())
+	if !crypto.ValidateSignatureValues(signature.V-27, r, s, false) {
+		return common.Address{}
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig[0:32], signature.R.Bytes())
+	copy(sig[32:64], signature.S.Bytes())
+	sig[64] = signature.V - 27
+	pub, err := crypto.SigToPub(digest.Bytes(), sig)
+	if err != nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(*pub)
+}
+
+// SignAuthorization signs the authorization with the given key for the given domain separator
+func SignAuthorization(key *ecdsa.PrivateKey, domainSeparator common.Hash, authorization Authorization) (Signature, error) {
+	digest := AuthorizationDigest(domainSeparator, authorization)
+	sig, err := crypto.Sign(digest.Bytes(), key)
+	if err != nil {
+		return Signature{}, err
+	}
+	return Signature{
+		V: sig[64] + 27,
+		R: common.BytesToHash(sig[0:32]),
+		S: common.BytesToHash(sig[32:64]),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAuthorization(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	bundler := common.HexToAddress("0x8888888888888888888888888888888888888888")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)})
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+
+	supplyAmount := uint256.MustFromDecimal("1000000000000000000")
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, supplyAmount))
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+
+	// the bundler cannot withdraw on behalf of the supplier until authorized
+	_, _, err = morpho.Withdraw(bundler, marketParams, supplyAmount, uint256.NewInt(0), supplier, bundler)
+	require.Equal(t, ErrorUnauthorized, err)
+
+	require.NoError(t, morpho.SetAuthorization(supplier, bundler, true))
+	require.Equal(t, ErrorAlreadySet, morpho.SetAuthorization(supplier, bundler, true))
+
+	_, _, err = morpho.Withdraw(bundler, marketParams, supplyAmount, uint256.NewInt(0), supplier, bundler)
+	require.NoError(t, err)
+
+	require.NoError(t, morpho.SetAuthorization(supplier, bundler, false))
+	err = morpho.WithdrawCollateral(bundler, marketParams, uint256.NewInt(1), supplier, bundler)
+	require.Equal(t, ErrorUnauthorized, err)
+}
+
+func TestSetAuthorizationWithSig(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	bundler := common.HexToAddress("0x8888888888888888888888888888888888888888")
+	morpho := NewMorpho(owner, owner)
+	morpho.ChainId = 1
+	morpho.Address = common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb")
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	authorizer := crypto.PubkeyToAddress(key.PublicKey)
+
+	authorization := Authorization{
+		Authorizer:   authorizer,
+		Authorized:   bundler,
+		IsAuthorized: true,
+		Nonce:        *uint256.NewInt(0),
+		Deadline:     *uint256.NewInt(morpho.BlockTimestamp + 3600),
+	}
+	signature, err := SignAuthorization(key, morpho.DomainSeparator(), authorization)
+	require.NoError(t, err)
+
+	t.Run("Expired signature", func(t *testing.T) {
+		expired := authorization
+		expired.Deadline = *uint256.NewInt(morpho.BlockTimestamp - 1)
+		err := morpho.SetAuthorizationWithSig(bundler, expired, signature)
+		require.Equal(t, ErrorSignatureExpired, err)
+	})
+
+	t.Run("Invalid nonce", func(t *testing.T) {
+		invalidNonce := authorization
+		invalidNonce.Nonce = *uint256.NewInt(1)
+		err := morpho.SetAuthorizationWithSig(bundler, invalidNonce, signature)
+		require.Equal(t, ErrorInvalidNonce, err)
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		tampered := authorization
+		tampered.Authorized = owner
+		err := morpho.SetAuthorizationWithSig(bundler, tampered, signature)
+		require.Equal(t, ErrorInvalidSignature, err)
+
+		invalidV := signature
+		invalidV.V = 29
+		err = morpho.SetAuthorizationWithSig(bundler, authorization, invalidV)
+		require.Equal(t, ErrorInvalidSignature, err)
+	})
+
+	t.Run("Valid signature", func(t *testing.T) {
+		require.NoError(t, morpho.SetAuthorizationWithSig(bundler, authorization, signature))
+
+		isAuthorized, err := morpho.getIsAuthorized(authorizer, bundler)
+		require.NoError(t, err)
+		require.True(t, isAuthorized)
+
+		nonce, _ := morpho.Nonce.Get(authorizer)
+		require.Equal(t, uint64(1), nonce.Uint64())
+
+		// the signature cannot be replayed
+		err = morpho.SetAuthorizationWithSig(bundler, authorization, signature)
+		require.Equal(t, ErrorInvalidNonce, err)
+	})
+}
@@ -0,0 +1,52 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// Callback interfaces based on IMorphoCallbacks.sol
+// https://github.com/morpho-org/morpho-blue/blob/main/src/interfaces/IMorphoCallbacks.sol
+// callbacks are invoked on the caller, which is resolved through Morpho.Callbacks.
+
+// MorphoLiquidateCallback is called by Liquidate if data is not empty
+type MorphoLiquidateCallback interface {
+	OnMorphoLiquidate(repaidAssets *uint256.Int, data []byte) error
+}
+
+// MorphoRepayCallback is called by Repay if data is not empty
+type MorphoRepayCallback interface {
+	OnMorphoRepay(assets *uint256.Int, data []byte) error
+}
+
+// MorphoSupplyCallback is called by Supply if data is not empty
+type MorphoSupplyCallback interface {
+	OnMorphoSupply(assets *uint256.Int, data []byte) error
+}
+
+// MorphoSupplyCollateralCallback is called by SupplyCollateral if data is not empty
+type MorphoSupplyCollateralCallback interface {
+	OnMorphoSupplyCollateral(assets *uint256.Int, data []byte) error
+}
+
+// MorphoFlashLoanCallback is called by FlashLoan
+type MorphoFlashLoanCallback interface {
+	OnMorphoFlashLoan(assets *uint256.Int, data []byte) error
+}
+
+// getCallback resolves the contract at caller and checks that it implements the callback T
+func getCallback[T any](m *Morpho, caller common.Address) (T, error) {
+	var callback T
+	contract, err := m.Callbacks.Get(caller)
+	if err != nil {
+		return callback, err
+	}
+	if contract == nil {
+		return callback, ErrorCallToNonContract
+	}
+	callback, ok := contract.(T)
+	if !ok {
+		return callback, ErrorFunctionNotFound
+	}
+	return callback, nil
+}
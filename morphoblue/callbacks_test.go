@@ -0,0 +1,138 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// leverageCallback borrows against the collateral being supplied
+type leverageCallback struct {
+	morpho       *Morpho
+	address      common.Address
+	marketParams MarketParams
+	borrowAssets *uint256.Int
+}
+
+func (c *leverageCallback) OnMorphoSupplyCollateral(assets *uint256.Int, data []byte) error {
+	_, _, err := c.morpho.Borrow(c.address, c.marketParams, c.borrowAssets, uint256.NewInt(0), c.address, c.address)
+	return err
+}
+
+// flashLoanCallback checks it holds the loan, and spends it if keep is set
+type flashLoanCallback struct {
+	morpho  *Morpho
+	address common.Address
+	token   common.Address
+	keep    bool
+}
+
+func (c *flashLoanCallback) OnMorphoFlashLoan(assets *uint256.Int, data []byte) error {
+	balance, err := c.morpho.BalanceOf(c.token, c.address)
+	if err != nil {
+		return err
+	}
+	if balance.Lt(assets) {
+		return ErrorInsufficientLiquidity
+	}
+	if c.keep {
+		return c.morpho.Deal(c.token, c.address, balance.Sub(balance, assets))
+	}
+	return nil
+}
+
+func TestCallbacks(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	bundler := common.HexToAddress("0x8888888888888888888888888888888888888888")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)})
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	morpho.Oracles.Set(marketParams.Oracle, &mockOracle{price: ORACLE_PRICE_SCALE})
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+
+	collateral := uint256.MustFromDecimal("100000000000000000000")
+	data := []byte{0x01}
+
+	t.Run("Callback on an account without code", func(t *testing.T) {
+		caller := common.HexToAddress("0x6666666666666666666666666666666666666666")
+		err := morpho.SupplyCollateral(caller, uint256.NewInt(0), marketParams, collateral, caller, data)
+		require.Equal(t, ErrorCallToNonContract, err)
+		// calling an account without code reverts without data, unlike the NO_CODE of token transfers
+		data, err := gosol.EncodeRevert(err)
+		require.NoError(t, err)
+		require.Empty(t, data)
+	})
+
+	t.Run("Callback not implemented", func(t *testing.T) {
+		caller := common.HexToAddress("0x7777777777777777777777777777777777777777")
+		require.NoError(t, morpho.Callbacks.Set(caller, struct{}{}))
+		_, _, err := morpho.Supply(caller, uint256.NewInt(0), marketParams, collateral, uint256.NewInt(0), caller, data)
+		require.Equal(t, ErrorFunctionNotFound, err)
+	})
+
+	t.Run("Callback borrows against the supplied collateral", func(t *testing.T) {
+		require.NoError(t, morpho.Callbacks.Set(bundler, &leverageCallback{
+			morpho:       morpho,
+			address:      bundler,
+			marketParams: marketParams,
+			borrowAssets: uint256.MustFromDecimal("80000000000000000000"),
+		}))
+		require.NoError(t, morpho.Deal(marketParams.CollateralToken, bundler, collateral))
+		err := morpho.SupplyCollateral(bundler, uint256.NewInt(0), marketParams, collateral, bundler, data)
+		require.NoError(t, err)
+
+		position, _ := morpho.getPosition(ComputeMarketId(marketParams), bundler)
+		require.Equal(t, "100000000000000000000", position.Collateral.String())
+		require.Equal(t, "80000000000000000000000000", position.BorrowShares.String())
+	})
+}
+
+func TestFlashLoan(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	bundler := common.HexToAddress("0x8888888888888888888888888888888888888888")
+	token := common.HexToAddress("0x3333333333333333333333333333333333333333")
+	morpho := NewMorpho(owner, owner)
+
+	callback := &flashLoanCallback{morpho: morpho, address: bundler, token: token}
+	require.NoError(t, morpho.Callbacks.Set(bundler, callback))
+	assets := uint256.MustFromDecimal("1000000000000000000000")
+
+	err := morpho.FlashLoan(bundler, uint256.NewInt(0), token, uint256.NewInt(0), nil)
+	require.Equal(t, ErrorZeroAssets, err)
+
+	// the contract does not hold the tokens yet
+	err = morpho.FlashLoan(bundler, uint256.NewInt(0), token, assets, nil)
+	require.Equal(t, ErrorTransferReverted, err)
+
+	require.NoError(t, morpho.Deal(token, morpho.Address, assets))
+	require.NoError(t, morpho.FlashLoan(bundler, uint256.NewInt(0), token, assets, nil))
+	balance, _ := morpho.BalanceOf(token, morpho.Address)
+	require.Equal(t, assets.String(), balance.String())
+	balance, _ = morpho.BalanceOf(token, bundler)
+	require.True(t, balance.IsZero())
+
+	callback.keep = true
+	err = morpho.FlashLoan(bundler, uint256.NewInt(0), token, assets, nil)
+	require.Equal(t, ErrorTransferFromReverted, err)
+	balance, _ = morpho.BalanceOf(token, morpho.Address)
+	require.Equal(t, assets.String(), balance.String())
+}
@@ -0,0 +1,300 @@
+package morphoblue
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+//go:embed abi/MorphoBlue.json
+var morphoBlueABIJSON []byte
+
+// MorphoBlueABI is the ABI of Morpho Blue, based on IMorpho.sol and EventsLib.sol
+var MorphoBlueABI = func() abi.ABI {
+	parsed, err := abi.JSON(bytes.NewReader(morphoBlueABIJSON))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// Call is a call to Morpho Blue, with the same arguments as its declaration in IMorpho.sol
+type Call interface {
+	// Method returns the name of the function in IMorpho.sol
+	Method() string
+	// Encode returns the calldata of the call
+	Encode() ([]byte, error)
+}
+
+type SupplyCall struct {
+	MarketParams MarketParams
+	Assets       uint256.Int
+	Shares       uint256.Int
+	OnBehalf     common.Address
+	Data         []byte
+}
+
+type WithdrawCall struct {
+	MarketParams MarketParams
+	Assets       uint256.Int
+	Shares       uint256.Int
+	OnBehalf     common.Address
+	Receiver     common.Address
+}
+
+type BorrowCall struct {
+	MarketParams MarketParams
+	Assets       uint256.Int
+	Shares       uint256.Int
+	OnBehalf     common.Address
+	Receiver     common.Address
+}
+
+type RepayCall struct {
+	MarketParams MarketParams
+	Assets       uint256.Int
+	Shares       uint256.Int
+	OnBehalf     common.Address
+	Data         []byte
+}
+
+type SupplyCollateralCall struct {
+	MarketParams MarketParams
+	Assets       uint256.Int
+	OnBehalf     common.Address
+	Data         []byte
+}
+
+type WithdrawCollateralCall struct {
+	MarketParams MarketParams
+	Assets       uint256.Int
+	OnBehalf     common.Address
+	Receiver     common.Address
+}
+
+type LiquidateCall struct {
+	MarketParams MarketParams
+	Borrower     common.Address
+	SeizedAssets uint256.Int
+	RepaidShares uint256.Int
+	Data         []byte
+}
+
+type FlashLoanCall struct {
+	Token  common.Address
+	Assets uint256.Int
+	Data   []byte
+}
+
+type SetAuthorizationCall struct {
+	Authorized      common.Address
+	NewIsAuthorized bool
+}
+
+type CreateMarketCall struct {
+	MarketParams MarketParams
+}
+
+func (SupplyCall) Method() string             { return "supply" }
+func (WithdrawCall) Method() string           { return "withdraw" }
+func (BorrowCall) Method() string             { return "borrow" }
+func (RepayCall) Method() string              { return "repay" }
+func (SupplyCollateralCall) Method() string   { return "supplyCollateral" }
+func (WithdrawCollateralCall) Method() string { return "withdrawCollateral" }
+func (LiquidateCall) Method() string          { return "liquidate" }
+func (FlashLoanCall) Method() string          { return "flashLoan" }
+func (SetAuthorizationCall) Method() string   { return "setAuthorization" }
+func (CreateMarketCall) Method() string       { return "createMarket" }
+
+func (c SupplyCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Assets.ToBig(), c.Shares.ToBig(), c.OnBehalf, toABIBytes(c.Data))
+}
+
+func (c WithdrawCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Assets.ToBig(), c.Shares.ToBig(), c.OnBehalf, c.Receiver)
+}
+
+func (c BorrowCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Assets.ToBig(), c.Shares.ToBig(), c.OnBehalf, c.Receiver)
+}
+
+func (c RepayCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Assets.ToBig(), c.Shares.ToBig(), c.OnBehalf, toABIBytes(c.Data))
+}
+
+func (c SupplyCollateralCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Assets.ToBig(), c.OnBehalf, toABIBytes(c.Data))
+}
+
+func (c WithdrawCollateralCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Assets.ToBig(), c.OnBehalf, c.Receiver)
+}
+
+func (c LiquidateCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams), c.Borrower, c.SeizedAssets.ToBig(), c.RepaidShares.ToBig(), toABIBytes(c.Data))
+}
+
+func (c FlashLoanCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), c.Token, c.Assets.ToBig(), toABIBytes(c.Data))
+}
+
+func (c SetAuthorizationCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), c.Authorized, c.NewIsAuthorized)
+}
+
+func (c CreateMarketCall) Encode() ([]byte, error) {
+	return MorphoBlueABI.Pack(c.Method(), toABIMarketParams(c.MarketParams))
+}
+
+// callDecoders build the calls from the arguments unpacked from the calldata
+var callDecoders = map[string]func(args []any) Call{
+	"supply": func(args []any) Call {
+		return SupplyCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Assets:       fromABIUint(args[1]),
+			Shares:       fromABIUint(args[2]),
+			OnBehalf:     args[3].(common.Address),
+			Data:         args[4].([]byte),
+		}
+	},
+	"withdraw": func(args []any) Call {
+		return WithdrawCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Assets:       fromABIUint(args[1]),
+			Shares:       fromABIUint(args[2]),
+			OnBehalf:     args[3].(common.Address),
+			Receiver:     args[4].(common.Address),
+		}
+	},
+	"borrow": func(args []any) Call {
+		return BorrowCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Assets:       fromABIUint(args[1]),
+			Shares:       fromABIUint(args[2]),
+			OnBehalf:     args[3].(common.Address),
+			Receiver:     args[4].(common.Address),
+		}
+	},
+	"repay": func(args []any) Call {
+		return RepayCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Assets:       fromABIUint(args[1]),
+			Shares:       fromABIUint(args[2]),
+			OnBehalf:     args[3].(common.Address),
+			Data:         args[4].([]byte),
+		}
+	},
+	"supplyCollateral": func(args []any) Call {
+		return SupplyCollateralCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Assets:       fromABIUint(args[1]),
+			OnBehalf:     args[2].(common.Address),
+			Data:         args[3].([]byte),
+		}
+	},
+	"withdrawCollateral": func(args []any) Call {
+		return WithdrawCollateralCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Assets:       fromABIUint(args[1]),
+			OnBehalf:     args[2].(common.Address),
+			Receiver:     args[3].(common.Address),
+		}
+	},
+	"liquidate": func(args []any) Call {
+		return LiquidateCall{
+			MarketParams: fromABIMarketParams(args[0]),
+			Borrower:     args[1].(common.Address),
+			SeizedAssets: fromABIUint(args[2]),
+			RepaidShares: fromABIUint(args[3]),
+			Data:         args[4].([]byte),
+		}
+	},
+	"flashLoan": func(args []any) Call {
+		return FlashLoanCall{
+			Token:  args[0].(common.Address),
+			Assets: fromABIUint(args[1]),
+			Data:   args[2].([]byte),
+		}
+	},
+	"setAuthorization": func(args []any) Call {
+		return SetAuthorizationCall{
+			Authorized:      args[0].(common.Address),
+			NewIsAuthorized: args[1].(bool),
+		}
+	},
+	"createMarket": func(args []any) Call {
+		return CreateMarketCall{
+			MarketParams: fromABIMarketParams(args[0]),
+		}
+	},
+}
+
+// DecodeCall returns the call encoded in calldata.
+// it fails with ErrorFunctionNotFound if the selector is not a Morpho Blue function,
+// and with ErrorUnsupportedCall if the function has no Call type
+func DecodeCall(calldata []byte) (Call, error) {
+	if len(calldata) < 4 {
+		return nil, ErrorFunctionNotFound
+	}
+	method, err := MorphoBlueABI.MethodById(calldata[:4])
+	if err != nil {
+		return nil, ErrorFunctionNotFound
+	}
+	decode, ok := callDecoders[method.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorUnsupportedCall, method.Name)
+	}
+	args, err := method.Inputs.Unpack(calldata[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method.Name, err)
+	}
+	return decode(args), nil
+}
+
+// abiMarketParams is the MarketParams tuple as packed by the abi package
+type abiMarketParams struct {
+	LoanToken       common.Address
+	CollateralToken common.Address
+	Oracle          common.Address
+	Irm             common.Address
+	Lltv            *big.Int
+}
+
+func toABIMarketParams(marketParams MarketParams) abiMarketParams {
+	return abiMarketParams{
+		LoanToken:       marketParams.LoanToken,
+		CollateralToken: marketParams.CollateralToken,
+		Oracle:          marketParams.Oracle,
+		Irm:             marketParams.Irm,
+		Lltv:            marketParams.Lltv.ToBig(),
+	}
+}
+
+func fromABIMarketParams(arg any) MarketParams {
+	marketParams := *abi.ConvertType(arg, new(abiMarketParams)).(*abiMarketParams)
+	return MarketParams{
+		LoanToken:       marketParams.LoanToken,
+		CollateralToken: marketParams.CollateralToken,
+		Oracle:          marketParams.Oracle,
+		Irm:             marketParams.Irm,
+		Lltv:            fromABIUint(marketParams.Lltv),
+	}
+}
+
+// fromABIUint converts an unpacked uint256, which always fits
+func fromABIUint(arg any) uint256.Int {
+	return *uint256.MustFromBig(arg.(*big.Int))
+}
+
+// toABIBytes packs nil as empty bytes
+func toABIBytes(data []byte) []byte {
+	if data == nil {
+		return []byte{}
+	}
+	return data
+}
@@ -0,0 +1,74 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalls(t *testing.T) {
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *uint256.MustFromDecimal("800000000000000000"),
+	}
+	user := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	assets := *uint256.MustFromDecimal("1000000000000000000")
+
+	for selector, call := range map[string]Call{
+		"0xa99aad89": SupplyCall{MarketParams: marketParams, Assets: assets, OnBehalf: user, Data: []byte{}},
+		"0x5c2bea49": WithdrawCall{MarketParams: marketParams, Shares: assets, OnBehalf: user, Receiver: user},
+		"0x50d8cd4b": BorrowCall{MarketParams: marketParams, Assets: assets, OnBehalf: user, Receiver: user},
+		"0x20b76e81": RepayCall{MarketParams: marketParams, Shares: assets, OnBehalf: user, Data: []byte{0x01}},
+		"0x238d6579": SupplyCollateralCall{MarketParams: marketParams, Assets: assets, OnBehalf: user, Data: []byte{}},
+		"0x8720316d": WithdrawCollateralCall{MarketParams: marketParams, Assets: assets, OnBehalf: user, Receiver: user},
+		"0xd8eabcb8": LiquidateCall{MarketParams: marketParams, Borrower: user, SeizedAssets: assets, Data: []byte{0x01, 0x02}},
+		"0xe0232b42": FlashLoanCall{Token: marketParams.LoanToken, Assets: assets, Data: []byte{}},
+		"0xeecea000": SetAuthorizationCall{Authorized: user, NewIsAuthorized: true},
+		"0x8c1358a2": CreateMarketCall{MarketParams: marketParams},
+	} {
+		t.Run(call.Method(), func(t *testing.T) {
+			calldata, err := call.Encode()
+			require.NoError(t, err)
+			require.Equal(t, selector, hexutil.Encode(calldata[:4]))
+
+			decoded, err := DecodeCall(calldata)
+			require.NoError(t, err)
+			require.Equal(t, call, decoded)
+		})
+	}
+
+	// createMarket((address,address,address,address,uint256)) is the selector followed by the 5 words of the tuple
+	calldata, err := CreateMarketCall{MarketParams: marketParams}.Encode()
+	require.NoError(t, err)
+	require.Equal(t, "0x8c1358a2"+
+		"0000000000000000000000003333333333333333333333333333333333333333"+
+		"0000000000000000000000004444444444444444444444444444444444444444"+
+		"0000000000000000000000002222222222222222222222222222222222222222"+
+		"0000000000000000000000001111111111111111111111111111111111111111"+
+		"0000000000000000000000000000000000000000000000000b1a2bc2ec500000", hexutil.Encode(calldata))
+
+	// nil data is encoded as empty bytes
+	calldata, err = SupplyCall{MarketParams: marketParams, Assets: assets, OnBehalf: user}.Encode()
+	require.NoError(t, err)
+	decoded, err := DecodeCall(calldata)
+	require.NoError(t, err)
+	require.Empty(t, decoded.(SupplyCall).Data)
+
+	_, err = DecodeCall([]byte{0xa9, 0x9a})
+	require.Equal(t, ErrorFunctionNotFound, err)
+	_, err = DecodeCall(hexutil.MustDecode("0xdeadbeef"))
+	require.Equal(t, ErrorFunctionNotFound, err)
+	_, err = DecodeCall(calldata[:100])
+	require.Error(t, err)
+
+	calldata, err = MorphoBlueABI.Pack("setOwner", user)
+	require.NoError(t, err)
+	_, err = DecodeCall(calldata)
+	require.ErrorIs(t, err, ErrorUnsupportedCall)
+}
@@ -0,0 +1,150 @@
+package morphoblue
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+)
+
+var extSloadsSelector = crypto.Keccak256([]byte("extSloads(bytes32[])"))[:4]
+
+// ChainInfoReader reads the chain id and block headers, implemented by ethclient.Client
+type ChainInfoReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// ChainReader reads the storage of a deployed Morpho contract through its extSloads function
+type ChainReader struct {
+	Ctx    context.Context
+	Caller bind.ContractCaller
+	// Chain reads the chain id and the header of BlockNumber, whose timestamp is the simulated block.timestamp
+	Chain  ChainInfoReader
+	Morpho common.Address
+	// BlockNumber is the block to read the storage at, nil for the latest block.
+	// NewMorphoFromChain pins it to the latest block, so that the storage loaded later is read at the same block
+	BlockNumber *big.Int
+}
+
+// ExtSloads returns the storage words at the given slots
+func (r *ChainReader) ExtSloads(slots []common.Hash) ([]common.Hash, error) {
+	data := make([]byte, 4+64+32*len(slots))
+	copy(data, extSloadsSelector)
+	data[4+31] = 0x20
+	binary.BigEndian.PutUint64(data[4+56:4+64], uint64(len(slots)))
+	for i, slot := range slots {
+		copy(data[4+64+32*i:], slot.Bytes())
+	}
+	out, err := r.Caller.CallContract(r.Ctx, ethereum.CallMsg{To: &r.Morpho, Data: data}, r.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// the result is abi encoded as bytes32[]: offset, length, then the words
+	if len(out) != 64+32*len(slots) {
+		return nil, fmt.Errorf("extSloads: unexpected result length %d for %d slots", len(out), len(slots))
+	}
+	offset, length := new(uint256.Int).SetBytes(out[:32]), new(uint256.Int).SetBytes(out[32:64])
+	if !offset.Eq(uint256.NewInt(32)) || !length.Eq(uint256.NewInt(uint64(len(slots)))) {
+		return nil, fmt.Errorf("extSloads: unexpected result encoding")
+	}
+	words := make([]common.Hash, len(slots))
+	for i := range words {
+		words[i] = common.BytesToHash(out[64+32*i : 96+32*i])
+	}
+	return words, nil
+}
+
+// NewMorphoFromChain creates a Morpho instance which loads the markets, positions and other mappings of the
+// contract deployed at reader.Morpho on first access, so that a simulation only reads the state it touches.
+// the owner, fee recipient and chain id are read when the instance is created, and the block timestamp is the one of
+// reader.BlockNumber, which is set to the latest block if it is nil.
+// IRMs, oracles and token balances are not read from chain, and must be registered like for NewMorpho.
+func NewMorphoFromChain(reader *ChainReader, options ...Option) (*Morpho, error) {
+	if reader.Chain == nil {
+		return nil, errors.New("chain reader: no chain info reader")
+	}
+	chainId, err := reader.Chain.ChainID(reader.Ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !chainId.IsUint64() {
+		return nil, fmt.Errorf("chain reader: chain id %s out of range", chainId)
+	}
+	header, err := reader.Chain.HeaderByNumber(reader.Ctx, reader.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if reader.BlockNumber == nil {
+		reader.BlockNumber = new(big.Int).Set(header.Number)
+	}
+	// the options can still override the block timestamp
+	options = append([]Option{WithBlockTimestamp(header.Time)}, options...)
+	words, err := reader.ExtSloads([]common.Hash{OWNER_SLOT, FEE_RECIPIENT_SLOT})
+	if err != nil {
+		return nil, err
+	}
+	m := NewMorpho(common.BytesToAddress(words[0].Bytes()), common.BytesToAddress(words[1].Bytes()), options...)
+	m.Address = reader.Morpho
+	m.ChainId = chainId.Uint64()
+	journal := m.Journal
+
+	m.Position = gosol.NewJournaledMapping(journal, gosol.NewLazyMapping(gosol.ReaderFunc[common.Hash, gosol.Mapping[common.Address, Position]](
+		func(id common.Hash) (gosol.Mapping[common.Address, Position], error) {
+			return lazyChainMapping(journal, reader, func(user common.Address) common.Hash {
+				return PositionSlot(id, user)
+			}, PositionCodec{}), nil
+		},
+	)))
+	m.Market = lazyChainMapping(journal, reader, MarketSlot, MarketCodec{})
+	m.IsIrmEnabled = lazyChainMapping(journal, reader, func(irm common.Address) common.Hash {
+		return gosol.MappingSlot(gosol.AddressKey(irm), IS_IRM_ENABLED_SLOT)
+	}, gosol.BoolCodec{})
+	m.IsLltvEnabled = lazyChainMapping(journal, reader, func(lltv uint256.Int) common.Hash {
+		return gosol.MappingSlot(gosol.Uint256Key(lltv), IS_LLTV_ENABLED_SLOT)
+	}, gosol.BoolCodec{})
+	m.IsAuthorized = gosol.NewJournaledMapping(journal, gosol.NewLazyMapping(gosol.ReaderFunc[common.Address, gosol.Mapping[common.Address, bool]](
+		func(authorizer common.Address) (gosol.Mapping[common.Address, bool], error) {
+			slot := gosol.MappingSlot(gosol.AddressKey(authorizer), IS_AUTHORIZED_SLOT)
+			return lazyChainMapping(journal, reader, func(authorized common.Address) common.Hash {
+				return gosol.MappingSlot(gosol.AddressKey(authorized), slot)
+			}, gosol.BoolCodec{}), nil
+		},
+	)))
+	m.Nonce = lazyChainMapping(journal, reader, func(user common.Address) common.Hash {
+		return gosol.MappingSlot(gosol.AddressKey(user), NONCE_SLOT)
+	}, gosol.Uint256Codec{})
+	m.IdToMarketParams = lazyChainMapping(journal, reader, MarketParamsSlot, MarketParamsCodec{})
+	return m, nil
+}
+
+// lazyChainMapping creates a journaled mapping loading the value of a key from the storage words starting at slot(key)
+func lazyChainMapping[K comparable, V any](
+	journal *gosol.Journal,
+	reader *ChainReader,
+	slot func(K) common.Hash,
+	codec gosol.StorageCodec[V],
+) gosol.Mapping[K, V] {
+	return gosol.NewJournaledMapping(journal, gosol.NewLazyMapping(gosol.ReaderFunc[K, V](func(key K) (V, error) {
+		slots := make([]common.Hash, codec.Words())
+		for i := range slots {
+			slots[i] = gosol.SlotOffset(slot(key), uint64(i))
+		}
+		words, err := reader.ExtSloads(slots)
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+		return codec.Decode(words)
+	})))
+}
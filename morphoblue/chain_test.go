@@ -0,0 +1,139 @@
+package morphoblue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// stubCaller serves extSloads calls from storage at block 1000, the latest one, recording the slots and blocks read
+type stubCaller struct {
+	storage gosol.MapStorage
+	reads   []common.Hash
+	blocks  []*big.Int
+}
+
+func (c *stubCaller) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (c *stubCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x00}, nil
+}
+
+func (c *stubCaller) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number != nil && number.Uint64() != 1000 {
+		return nil, errors.New("unexpected block number")
+	}
+	return &types.Header{Number: big.NewInt(1000), Time: 1700000000}, nil
+}
+
+func (c *stubCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.blocks = append(c.blocks, blockNumber)
+	if !bytes.Equal(call.Data[:4], extSloadsSelector) {
+		return nil, errors.New("execution reverted")
+	}
+	n := new(big.Int).SetBytes(call.Data[36:68]).Int64()
+	out := make([]byte, 64+32*n)
+	out[31] = 0x20
+	copy(out[32:64], call.Data[36:68])
+	for i := int64(0); i < n; i++ {
+		slot := common.BytesToHash(call.Data[68+32*i : 100+32*i])
+		c.reads = append(c.reads, slot)
+		value := c.storage[slot]
+		copy(out[64+32*i:], value.Bytes())
+	}
+	return out, nil
+}
+
+func TestMorphoFromChain(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *uint256.MustFromDecimal("800000000000000000"),
+	}
+	marketId := ComputeMarketId(marketParams)
+
+	// the chain holds a market with 1000 tokens supplied by the supplier
+	onchain, storage, err := NewStorageOverride()
+	require.NoError(t, err)
+	storage[OWNER_SLOT] = gosol.AddressKey(owner)
+	market := Market{
+		TotalSupplyAssets: *uint256.MustFromDecimal("1000000000000000000000"),
+		TotalSupplyShares: *uint256.MustFromDecimal("1000000000000000000000000000"),
+		LastUpdate:        *uint256.NewInt(1700000000),
+	}
+	require.NoError(t, onchain.Market.Set(marketId, market))
+	require.NoError(t, onchain.IdToMarketParams.Set(marketId, marketParams))
+	require.NoError(t, onchain.setPosition(marketId, supplier, Position{SupplyShares: market.TotalSupplyShares}))
+
+	caller := &stubCaller{storage: storage}
+	morpho, err := NewMorphoFromChain(&ChainReader{
+		Ctx:         context.Background(),
+		Caller:      caller,
+		Chain:       caller,
+		Morpho:      common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb"),
+		BlockNumber: big.NewInt(1000),
+	})
+	require.NoError(t, err)
+	require.Equal(t, owner, morpho.Owner)
+	require.Len(t, caller.reads, 2)
+	// the block timestamp is the one of the block the storage is read at
+	require.Equal(t, uint64(1700000000), morpho.BlockTimestamp)
+	require.Equal(t, uint64(1), morpho.ChainId)
+	require.Equal(t, DomainSeparator(1, morpho.Address), morpho.DomainSeparator())
+
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)}))
+	withdrawn := uint256.MustFromDecimal("400000000000000000000")
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, morpho.Address, withdrawn))
+	_, _, err = morpho.Withdraw(supplier, marketParams, withdrawn, uint256.NewInt(0), supplier, supplier)
+	require.NoError(t, err)
+
+	// only the market and the position of the supplier are loaded, and they are loaded once
+	reads := len(caller.reads)
+	require.Equal(t, 2+3+2, reads)
+	position, err := morpho.getPosition(marketId, supplier)
+	require.NoError(t, err)
+	require.Equal(t, "600000000000000000000000000", position.SupplyShares.String())
+	loaded, err := morpho.Market.Get(marketId)
+	require.NoError(t, err)
+	require.Equal(t, "600000000000000000000", loaded.TotalSupplyAssets.String())
+	require.Len(t, caller.reads, reads)
+
+	// the chain is never written to
+	position, _ = onchain.getPosition(marketId, supplier)
+	require.Equal(t, market.TotalSupplyShares, position.SupplyShares)
+
+	t.Run("The latest block is pinned", func(t *testing.T) {
+		caller := &stubCaller{storage: storage}
+		reader := &ChainReader{
+			Ctx:    context.Background(),
+			Caller: caller,
+			Chain:  caller,
+			Morpho: common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb"),
+		}
+		morpho, err := NewMorphoFromChain(reader)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(1000), reader.BlockNumber)
+		_, err = morpho.Market.Get(marketId)
+		require.NoError(t, err)
+		require.Len(t, caller.blocks, 2)
+		for _, block := range caller.blocks {
+			require.Equal(t, big.NewInt(1000), block)
+		}
+	})
+}
@@ -14,3 +14,15 @@ var (
 	MaxUint128       = uint256.Int{math.MaxUint64, math.MaxUint64, 0, 0}
 	One              = uint256.NewInt(1)
 )
+
+// Morpho Blue constants based on ConstantsLib.sol
+var (
+	// MAX_FEE is the maximum fee a market can have (25%)
+	MAX_FEE = uint256.MustFromDecimal("250000000000000000")
+	// ORACLE_PRICE_SCALE is the scale of the prices returned by oracles
+	ORACLE_PRICE_SCALE = uint256.MustFromDecimal("1000000000000000000000000000000000000")
+	// LIQUIDATION_CURSOR is the cursor used to compute the liquidation incentive factor (30%)
+	LIQUIDATION_CURSOR = uint256.MustFromDecimal("300000000000000000")
+	// MAX_LIQUIDATION_INCENTIVE_FACTOR is the maximum liquidation incentive factor (115%)
+	MAX_LIQUIDATION_INCENTIVE_FACTOR = uint256.MustFromDecimal("1150000000000000000")
+)
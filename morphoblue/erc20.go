@@ -0,0 +1,72 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+)
+
+// BalanceOf returns the ERC20 balance of account in token, as tracked by the simulator
+func (m *Morpho) BalanceOf(token, account common.Address) (*uint256.Int, error) {
+	balances, err := m.Balance.Get(token)
+	if err != nil || balances == nil {
+		return new(uint256.Int), err
+	}
+	balance, err := balances.Get(account)
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// Deal sets the ERC20 balance of account in token to amount
+func (m *Morpho) Deal(token, account common.Address, amount *uint256.Int) error {
+	balances, err := m.Balance.Get(token)
+	if err != nil {
+		return err
+	}
+	if balances == nil {
+		balances = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Address, uint256.Int](m.factory))
+		if err := m.Balance.Set(token, balances); err != nil {
+			return err
+		}
+	}
+	return balances.Set(account, *amount)
+}
+
+// safeTransfer transfers amount of token from the contract to to, mirroring SafeTransferLib.safeTransfer
+func (m *Morpho) safeTransfer(token, to common.Address, amount *uint256.Int) error {
+	if err := m.transfer(token, m.Address, to, amount); err != nil {
+		return ErrorTransferReverted
+	}
+	return nil
+}
+
+// safeTransferFrom transfers amount of token from from to the contract, mirroring SafeTransferLib.safeTransferFrom
+func (m *Morpho) safeTransferFrom(token, from common.Address, amount *uint256.Int) error {
+	if err := m.transfer(token, from, m.Address, amount); err != nil {
+		return ErrorTransferFromReverted
+	}
+	return nil
+}
+
+func (m *Morpho) transfer(token, from, to common.Address, amount *uint256.Int) error {
+	fromBalance, err := m.BalanceOf(token, from)
+	if err != nil {
+		return err
+	}
+	if err := sub(fromBalance, fromBalance, amount); err != nil {
+		return err
+	}
+	if err := m.Deal(token, from, fromBalance); err != nil {
+		return err
+	}
+	toBalance, err := m.BalanceOf(token, to)
+	if err != nil {
+		return err
+	}
+	if err := add(toBalance, toBalance, amount); err != nil {
+		return err
+	}
+	return m.Deal(token, to, toBalance)
+}
@@ -1,50 +1,144 @@
 package morphoblue
 
-import "errors"
+import (
+	"errors"
 
-// Morpho Blue error definitions based on ErrorsLib.sol
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+)
+
+// Morpho Blue error definitions based on ErrorsLib.sol, reverting with the same reason strings
 var (
 	// Ownership errors
-	ErrorNotOwner = errors.New("not owner")
+	ErrorNotOwner = revert("not owner")
 
 	// Market configuration errors
-	ErrorMaxLltvExceeded       = errors.New("max LLTV exceeded")
-	ErrorMaxFeeExceeded        = errors.New("max fee exceeded")
-	ErrorAlreadySet            = errors.New("already set")
-	ErrorIrmNotEnabled         = errors.New("IRM not enabled")
-	ErrorLltvNotEnabled        = errors.New("LLTV not enabled")
-	ErrorMarketAlreadyCreated  = errors.New("market already created")
-	ErrorNoCode                = errors.New("no code")
-	ErrorMarketNotCreated      = errors.New("market not created")
+	ErrorMaxLltvExceeded      = revert("max LLTV exceeded")
+	ErrorMaxFeeExceeded       = revert("max fee exceeded")
+	ErrorAlreadySet           = revert("already set")
+	ErrorIrmNotEnabled        = revert("IRM not enabled")
+	ErrorLltvNotEnabled       = revert("LLTV not enabled")
+	ErrorMarketAlreadyCreated = revert("market already created")
+	ErrorNoCode               = revert("no code")
+	ErrorMarketNotCreated     = revert("market not created")
 
 	// Input validation errors
-	ErrorInconsistentInput = errors.New("inconsistent input")
-	ErrorZeroAssets        = errors.New("zero assets")
-	ErrorZeroAddress       = errors.New("zero address")
+	ErrorInconsistentInput = revert("inconsistent input")
+	ErrorZeroAssets        = revert("zero assets")
+	ErrorZeroAddress       = revert("zero address")
 
 	// Authorization errors
-	ErrorUnauthorized = errors.New("unauthorized")
+	ErrorUnauthorized = revert("unauthorized")
 
 	// Position errors
-	ErrorInsufficientCollateral = errors.New("insufficient collateral")
-	ErrorInsufficientLiquidity  = errors.New("insufficient liquidity")
-	ErrorHealthyPosition        = errors.New("position is healthy")
+	ErrorInsufficientCollateral = revert("insufficient collateral")
+	ErrorInsufficientLiquidity  = revert("insufficient liquidity")
+	ErrorHealthyPosition        = revert("position is healthy")
 
 	// Signature errors
-	ErrorInvalidSignature = errors.New("invalid signature")
-	ErrorSignatureExpired = errors.New("signature expired")
-	ErrorInvalidNonce     = errors.New("invalid nonce")
+	ErrorInvalidSignature = revert("invalid signature")
+	ErrorSignatureExpired = revert("signature expired")
+	ErrorInvalidNonce     = revert("invalid nonce")
 
 	// Transfer errors
-	ErrorTransferReverted        = errors.New("transfer reverted")
-	ErrorTransferReturnedFalse   = errors.New("transfer returned false")
-	ErrorTransferFromReverted    = errors.New("transferFrom reverted")
-	ErrorTransferFromReturnedFalse = errors.New("transferFrom returned false")
+	ErrorTransferReverted          = revert("transfer reverted")
+	ErrorTransferReturnedFalse     = revert("transfer returned false")
+	ErrorTransferFromReverted      = revert("transferFrom reverted")
+	ErrorTransferFromReturnedFalse = revert("transferFrom returned false")
 
 	// Overflow errors
-	ErrorMaxUint128Exceeded = errors.New("max uint128 exceeded")
+	ErrorMaxUint128Exceeded = revert("max uint128 exceeded")
+
+	// Math errors, raised as compiler panics
+	ErrorDivideByZero     error = &gosol.PanicError{Code: gosol.PanicDivisionByZero, Message: "divide by zero"}
+	ErrorUint256Overflow  error = &gosol.PanicError{Code: gosol.PanicArithmetic, Message: "uint256 overflow"}
+	ErrorUint256Underflow error = &gosol.PanicError{Code: gosol.PanicArithmetic, Message: "uint256 underflow"}
+
+	// Oracle errors, based on the ErrorsLib.sol of morpho-blue-oracles
+	ErrorNegativeAnswer                = revert("negative answer")
+	ErrorVaultConversionSampleIsNotOne = revert("vault conversion sample is not one")
+	ErrorZeroVaultConversionSample     = revert("vault conversion sample is zero")
+
+	// MetaMorpho errors, based on the ErrorsLib.sol of metamorpho, raised as custom errors
+	ErrorNotCuratorRole                         = customError("NotCuratorRole()")
+	ErrorNotAllocatorRole                       = customError("NotAllocatorRole()")
+	ErrorNotGuardianRole                        = customError("NotGuardianRole()")
+	ErrorNotCuratorNorGuardianRole              = customError("NotCuratorNorGuardianRole()")
+	ErrorUnauthorizedMarket                     = customError("UnauthorizedMarket(bytes32)")
+	ErrorInconsistentAsset                      = customError("InconsistentAsset(bytes32)")
+	ErrorSupplyCapExceeded                      = customError("SupplyCapExceeded(bytes32)")
+	ErrorAlreadyPending                         = customError("AlreadyPending()")
+	ErrorPendingCap                             = customError("PendingCap(bytes32)")
+	ErrorPendingRemoval                         = customError("PendingRemoval()")
+	ErrorNonZeroCap                             = customError("NonZeroCap()")
+	ErrorDuplicateMarket                        = customError("DuplicateMarket(bytes32)")
+	ErrorInvalidMarketRemovalNonZeroCap         = customError("InvalidMarketRemovalNonZeroCap(bytes32)")
+	ErrorInvalidMarketRemovalNonZeroSupply      = customError("InvalidMarketRemovalNonZeroSupply(bytes32)")
+	ErrorInvalidMarketRemovalTimelockNotElapsed = customError("InvalidMarketRemovalTimelockNotElapsed(bytes32)")
+	ErrorNoPendingValue                         = customError("NoPendingValue()")
+	ErrorNotEnoughLiquidity                     = customError("NotEnoughLiquidity()")
+	ErrorMarketNotEnabled                       = customError("MarketNotEnabled(bytes32)")
+	ErrorAboveMaxTimelock                       = customError("AboveMaxTimelock()")
+	ErrorBelowMinTimelock                       = customError("BelowMinTimelock()")
+	ErrorTimelockNotElapsed                     = customError("TimelockNotElapsed()")
+	ErrorMaxQueueLengthExceeded                 = customError("MaxQueueLengthExceeded()")
+	ErrorZeroFeeRecipient                       = customError("ZeroFeeRecipient()")
+	ErrorInconsistentReallocation               = customError("InconsistentReallocation()")
+	ErrorAllCapsReached                         = customError("AllCapsReached()")
+
+	// VaultV2 errors, based on the ErrorsLib.sol of vault-v2, raised as custom errors
+	ErrorAbsoluteCapExceeded      = customError("AbsoluteCapExceeded()")
+	ErrorAbsoluteCapNotDecreasing = customError("AbsoluteCapNotDecreasing()")
+	ErrorAbsoluteCapNotIncreasing = customError("AbsoluteCapNotIncreasing()")
+	ErrorRelativeCapAboveOne      = customError("RelativeCapAboveOne()")
+	ErrorRelativeCapExceeded      = customError("RelativeCapExceeded()")
+	ErrorRelativeCapNotDecreasing = customError("RelativeCapNotDecreasing()")
+	ErrorRelativeCapNotIncreasing = customError("RelativeCapNotIncreasing()")
+	ErrorZeroAbsoluteCap          = customError("ZeroAbsoluteCap()")
+	ErrorZeroAllocation           = customError("ZeroAllocation()")
+	ErrorAutomaticallyTimelocked  = customError("AutomaticallyTimelocked()")
+	ErrorDataAlreadyPending       = customError("DataAlreadyPending()")
+	ErrorDataNotTimelocked        = customError("DataNotTimelocked()")
+	ErrorTimelockNotExpired       = customError("TimelockNotExpired()")
+	ErrorTimelockNotDecreasing    = customError("TimelockNotDecreasing()")
+	ErrorTimelockNotIncreasing    = customError("TimelockNotIncreasing()")
+	ErrorCannotReceiveShares      = customError("CannotReceiveShares()")
+	ErrorCannotReceiveAssets      = customError("CannotReceiveAssets()")
+	ErrorCannotSendShares         = customError("CannotSendShares()")
+	ErrorCannotSendAssets         = customError("CannotSendAssets()")
+	ErrorCastOverflow             = customError("CastOverflow()")
+	ErrorFeeInvariantBroken       = customError("FeeInvariantBroken()")
+	ErrorFeeTooHigh               = customError("FeeTooHigh()")
+	ErrorInvalidSigner            = customError("InvalidSigner()")
+	ErrorMaxRateTooHigh           = customError("MaxRateTooHigh()")
+	ErrorNotAdapter               = customError("NotAdapter()")
+	ErrorNotInAdapterRegistry     = customError("NotInAdapterRegistry()")
+	ErrorPenaltyTooHigh           = customError("PenaltyTooHigh()")
+	ErrorPermitDeadlineExpired    = customError("PermitDeadlineExpired()")
+
+	// ERC20 and ERC4626 errors, based on the IERC6093 and ERC4626 errors of OpenZeppelin
+	ErrorERC20InsufficientBalance   = customError("ERC20InsufficientBalance(address,uint256,uint256)")
+	ErrorERC20InsufficientAllowance = customError("ERC20InsufficientAllowance(address,uint256,uint256)")
+	ErrorERC4626ExceededMaxDeposit  = customError("ERC4626ExceededMaxDeposit(address,uint256,uint256)")
+	ErrorERC4626ExceededMaxMint     = customError("ERC4626ExceededMaxMint(address,uint256,uint256)")
+	ErrorERC4626ExceededMaxWithdraw = customError("ERC4626ExceededMaxWithdraw(address,uint256,uint256)")
+	ErrorERC4626ExceededMaxRedeem   = customError("ERC4626ExceededMaxRedeem(address,uint256,uint256)")
+
+	// ABI errors, which are not raised by the contracts
+	ErrorUnsupportedCall = errors.New("unsupported call")
+	ErrorUnknownEvent    = errors.New("unknown event")
+	ErrorMalformedLog    = errors.New("malformed log")
+
+	// EVM errors, which revert without data
+	ErrorNonPayable        = gosol.NewEmptyRevert("non-payable")
+	ErrorFunctionNotFound  = gosol.NewEmptyRevert("function not found")
+	ErrorCallToNonContract = gosol.NewEmptyRevert("call to non-contract")
+)
+
+func revert(reason string) error {
+	return &gosol.RevertError{Reason: reason}
+}
 
-	// Math errors
-	ErrorDivideByZero    = errors.New("divide by zero")
-	ErrorUint256Overflow = errors.New("uint256 overflow")
-)
\ No newline at end of file
+// customError is a sentinel matching the custom error with the given signature, whatever its arguments
+func customError(signature string) error {
+	return &gosol.CustomError{Selector: gosol.Selector(signature), Signature: signature}
+}
@@ -0,0 +1,28 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorsRevert(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	morpho := NewMorpho(owner, owner)
+
+	err := morpho.SetOwner(common.Address{}, owner)
+	require.ErrorIs(t, err, gosol.Require(false, "not owner"))
+	data, err := gosol.EncodeRevert(err)
+	require.NoError(t, err)
+
+	decoded, err := gosol.DecodeRevert(data)
+	require.NoError(t, err)
+	require.ErrorIs(t, decoded, ErrorNotOwner)
+
+	require.ErrorIs(t, ErrorUint256Underflow, gosol.Panic(gosol.PanicArithmetic))
+	require.NotErrorIs(t, ErrorUint256Overflow, ErrorUint256Underflow)
+	require.ErrorIs(t, ErrorDivideByZero, gosol.Panic(gosol.PanicDivisionByZero))
+	require.ErrorIs(t, ErrorNonPayable, gosol.ErrEmptyRevert)
+}
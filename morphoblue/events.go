@@ -0,0 +1,154 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// Event is an event emitted by Morpho, with the same fields as its declaration in EventsLib.sol
+// https://github.com/morpho-org/morpho-blue/blob/main/src/libraries/EventsLib.sol
+type Event interface {
+	// EventName returns the name of the event in EventsLib.sol
+	EventName() string
+}
+
+type SetOwnerEvent struct {
+	NewOwner common.Address
+}
+
+type SetFeeEvent struct {
+	Id     common.Hash
+	NewFee uint256.Int
+}
+
+type SetFeeRecipientEvent struct {
+	NewFeeRecipient common.Address
+}
+
+type EnableIrmEvent struct {
+	Irm common.Address
+}
+
+type EnableLltvEvent struct {
+	Lltv uint256.Int
+}
+
+type CreateMarketEvent struct {
+	Id           common.Hash
+	MarketParams MarketParams
+}
+
+type SupplyEvent struct {
+	Id       common.Hash
+	Caller   common.Address
+	OnBehalf common.Address
+	Assets   uint256.Int
+	Shares   uint256.Int
+}
+
+type WithdrawEvent struct {
+	Id       common.Hash
+	Caller   common.Address
+	OnBehalf common.Address
+	Receiver common.Address
+	Assets   uint256.Int
+	Shares   uint256.Int
+}
+
+type BorrowEvent struct {
+	Id       common.Hash
+	Caller   common.Address
+	OnBehalf common.Address
+	Receiver common.Address
+	Assets   uint256.Int
+	Shares   uint256.Int
+}
+
+type RepayEvent struct {
+	Id       common.Hash
+	Caller   common.Address
+	OnBehalf common.Address
+	Assets   uint256.Int
+	Shares   uint256.Int
+}
+
+type SupplyCollateralEvent struct {
+	Id       common.Hash
+	Caller   common.Address
+	OnBehalf common.Address
+	Assets   uint256.Int
+}
+
+type WithdrawCollateralEvent struct {
+	Id       common.Hash
+	Caller   common.Address
+	OnBehalf common.Address
+	Receiver common.Address
+	Assets   uint256.Int
+}
+
+type LiquidateEvent struct {
+	Id            common.Hash
+	Caller        common.Address
+	Borrower      common.Address
+	RepaidAssets  uint256.Int
+	RepaidShares  uint256.Int
+	SeizedAssets  uint256.Int
+	BadDebtAssets uint256.Int
+	BadDebtShares uint256.Int
+}
+
+type FlashLoanEvent struct {
+	Caller common.Address
+	Token  common.Address
+	Assets uint256.Int
+}
+
+type SetAuthorizationEvent struct {
+	Caller          common.Address
+	Authorizer      common.Address
+	Authorized      common.Address
+	NewIsAuthorized bool
+}
+
+type IncrementNonceEvent struct {
+	Caller     common.Address
+	Authorizer common.Address
+	UsedNonce  uint256.Int
+}
+
+type AccrueInterestEvent struct {
+	Id             common.Hash
+	PrevBorrowRate uint256.Int
+	Interest       uint256.Int
+	FeeShares      uint256.Int
+}
+
+func (SetOwnerEvent) EventName() string           { return "SetOwner" }
+func (SetFeeEvent) EventName() string             { return "SetFee" }
+func (SetFeeRecipientEvent) EventName() string    { return "SetFeeRecipient" }
+func (EnableIrmEvent) EventName() string          { return "EnableIrm" }
+func (EnableLltvEvent) EventName() string         { return "EnableLltv" }
+func (CreateMarketEvent) EventName() string       { return "CreateMarket" }
+func (SupplyEvent) EventName() string             { return "Supply" }
+func (WithdrawEvent) EventName() string           { return "Withdraw" }
+func (BorrowEvent) EventName() string             { return "Borrow" }
+func (RepayEvent) EventName() string              { return "Repay" }
+func (SupplyCollateralEvent) EventName() string   { return "SupplyCollateral" }
+func (WithdrawCollateralEvent) EventName() string { return "WithdrawCollateral" }
+func (LiquidateEvent) EventName() string          { return "Liquidate" }
+func (FlashLoanEvent) EventName() string          { return "FlashLoan" }
+func (SetAuthorizationEvent) EventName() string   { return "SetAuthorization" }
+func (IncrementNonceEvent) EventName() string     { return "IncrementNonce" }
+func (AccrueInterestEvent) EventName() string     { return "AccrueInterest" }
+
+// emit appends the event to the logs of the simulation
+func (m *Morpho) emit(event Event) {
+	m.Journal.Append(func() error {
+		if len(m.Logs) > 0 {
+			m.Logs = m.Logs[:len(m.Logs)-1]
+		}
+		return nil
+	})
+	m.Logs = append(m.Logs, event)
+}
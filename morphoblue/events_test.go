@@ -0,0 +1,108 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func eventNames(events []Event) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.EventName()
+	}
+	return names
+}
+
+func TestEvents(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	marketId := ComputeMarketId(marketParams)
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(1000000000)}))
+	require.NoError(t, morpho.Oracles.Set(marketParams.Oracle, &mockOracle{price: ORACLE_PRICE_SCALE}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	require.NoError(t, morpho.SetFee(owner, marketParams, MAX_FEE))
+	require.Equal(t, []string{"EnableIrm", "EnableLltv", "CreateMarket", "SetFee"}, eventNames(morpho.Logs))
+	require.Equal(t, CreateMarketEvent{Id: marketId, MarketParams: marketParams}, morpho.Logs[2])
+
+	morpho.Logs = nil
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+	require.NoError(t, morpho.Deal(marketParams.CollateralToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+	require.NoError(t, morpho.SupplyCollateral(borrower, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("100000000000000000000"), borrower, nil))
+	_, _, err = morpho.Borrow(borrower, marketParams, uint256.MustFromDecimal("50000000000000000000"), uint256.NewInt(0), borrower, borrower)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Supply", "SupplyCollateral", "Borrow"}, eventNames(morpho.Logs))
+	require.Equal(t, SupplyEvent{
+		Id:       marketId,
+		Caller:   supplier,
+		OnBehalf: supplier,
+		Assets:   *uint256.MustFromDecimal("1000000000000000000000"),
+		Shares:   *uint256.MustFromDecimal("1000000000000000000000000000"),
+	}, morpho.Logs[0])
+
+	// interest is accrued, and logged, before the repayment
+	morpho.Logs = nil
+	morpho.BlockTimestamp += 31536000
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+	_, _, err = morpho.Repay(borrower, uint256.NewInt(0), marketParams, uint256.NewInt(0), uint256.MustFromDecimal("50000000000000000000000000"), borrower, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"AccrueInterest", "Repay"}, eventNames(morpho.Logs))
+	accrueInterest := morpho.Logs[0].(AccrueInterestEvent)
+	require.Equal(t, "1000000000", accrueInterest.PrevBorrowRate.String())
+	require.Equal(t, "1601924342070988800", accrueInterest.Interest.String())
+	require.False(t, accrueInterest.FeeShares.IsZero())
+	repay := morpho.Logs[1].(RepayEvent)
+	require.Equal(t, "51601924342070988800", repay.Assets.String())
+}
+
+// loggedIRM records the events emitted by Morpho when it is called
+type loggedIRM struct {
+	morpho *Morpho
+	logs   []string
+}
+
+func (irm *loggedIRM) BorrowRate(marketParams MarketParams, market Market) (*uint256.Int, error) {
+	irm.logs = eventNames(irm.morpho.Logs)
+	return uint256.NewInt(0), nil
+}
+
+func TestCreateMarketEventOrder(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	morpho := NewMorpho(owner, owner)
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	marketParams := MarketParams{
+		LoanToken: common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		Irm:       common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:      *lltv,
+	}
+	require.NoError(t, morpho.EnableIrm(owner, marketParams.Irm))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+
+	// the event is reverted with the market if the IRM has no code
+	require.ErrorIs(t, morpho.CreateMarket(owner, marketParams), ErrorCallToNonContract)
+	require.Equal(t, []string{"EnableIrm", "EnableLltv"}, eventNames(morpho.Logs))
+
+	// the IRM is called after the event is emitted, like Morpho.sol
+	irm := &loggedIRM{morpho: morpho}
+	require.NoError(t, morpho.Irms.Set(marketParams.Irm, irm))
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	require.Equal(t, []string{"EnableIrm", "EnableLltv", "CreateMarket"}, irm.logs)
+}
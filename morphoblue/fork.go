@@ -0,0 +1,87 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+)
+
+// ForkableIRM is implemented by stateful IRMs, so that forks of Morpho do not share their state
+type ForkableIRM interface {
+	IRM
+	// Fork returns a copy-on-write overlay of the IRM, used by the given fork of Morpho
+	Fork(morpho *Morpho) IRM
+}
+
+// ForkableOracle is implemented by stateful oracles, so that forks of Morpho do not share their state
+type ForkableOracle interface {
+	Oracle
+	// Fork returns a copy of the oracle, used by the given fork of Morpho
+	Fork(morpho *Morpho) Oracle
+}
+
+// ForkableCallback is implemented by stateful callback contracts, so that forks of Morpho do not share their state
+type ForkableCallback interface {
+	// Fork returns a copy of the contract, used by the given fork of Morpho
+	Fork(morpho *Morpho) any
+}
+
+// Fork returns a copy-on-write overlay of the whole contract state, including the ledger and forkable IRMs,
+// oracles and callback contracts.
+// writes to the fork are not visible to m, and reads of untouched state fall through to m.
+// m must not be modified while it has forks in use, but its forks can be used concurrently with each other.
+// the fork has its own journal, and starts with no logs.
+func (m *Morpho) Fork() *Morpho {
+	journal := gosol.NewJournal()
+	fork := &Morpho{
+		Owner:            m.Owner,
+		FeeRecipient:     m.FeeRecipient,
+		Position:         forkMapping(journal, m.Position, forkNestedMapping[common.Address, Position](journal)),
+		Market:           forkMapping(journal, m.Market, nil),
+		IsIrmEnabled:     forkMapping(journal, m.IsIrmEnabled, nil),
+		IsLltvEnabled:    forkMapping(journal, m.IsLltvEnabled, nil),
+		IsAuthorized:     forkMapping(journal, m.IsAuthorized, forkNestedMapping[common.Address, bool](journal)),
+		Nonce:            forkMapping(journal, m.Nonce, nil),
+		IdToMarketParams: forkMapping(journal, m.IdToMarketParams, nil),
+		Address:          m.Address,
+		ChainId:          m.ChainId,
+		BlockTimestamp:   m.BlockTimestamp,
+		Balance:          forkMapping(journal, m.Balance, forkNestedMapping[common.Address, uint256.Int](journal)),
+		Journal:          journal,
+		factory:          m.factory,
+	}
+	fork.Irms = gosol.NewOverlayMapping(m.Irms, func(irm IRM) IRM {
+		if forkable, ok := irm.(ForkableIRM); ok {
+			return forkable.Fork(fork)
+		}
+		return irm
+	})
+	fork.Oracles = gosol.NewOverlayMapping(m.Oracles, func(oracle Oracle) Oracle {
+		if forkable, ok := oracle.(ForkableOracle); ok {
+			return forkable.Fork(fork)
+		}
+		return oracle
+	})
+	fork.Callbacks = gosol.NewOverlayMapping(m.Callbacks, func(contract any) any {
+		if forkable, ok := contract.(ForkableCallback); ok {
+			return forkable.Fork(fork)
+		}
+		return contract
+	})
+	return fork
+}
+
+// forkMapping creates a journaled copy-on-write overlay of base
+func forkMapping[K comparable, V any](journal *gosol.Journal, base gosol.Mapping[K, V], fork func(V) V) gosol.Mapping[K, V] {
+	return gosol.NewJournaledMapping(journal, gosol.NewOverlayMapping(base, fork))
+}
+
+// forkNestedMapping returns the function forking the inner mappings of a nested mapping
+func forkNestedMapping[K comparable, V any](journal *gosol.Journal) func(gosol.Mapping[K, V]) gosol.Mapping[K, V] {
+	return func(inner gosol.Mapping[K, V]) gosol.Mapping[K, V] {
+		if inner == nil {
+			return nil
+		}
+		return forkMapping(journal, inner, nil)
+	}
+}
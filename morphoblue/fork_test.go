@@ -0,0 +1,219 @@
+package morphoblue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFork(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	irm := NewAdaptiveCurveIrm(morpho)
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	marketId := ComputeMarketId(marketParams)
+	require.NoError(t, morpho.Irms.Set(irmAddr, irm))
+	require.NoError(t, morpho.Oracles.Set(marketParams.Oracle, &mockOracle{price: ORACLE_PRICE_SCALE}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+	require.NoError(t, morpho.Deal(marketParams.CollateralToken, borrower, uint256.MustFromDecimal("1000000000000000000000")))
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+	require.NoError(t, morpho.SupplyCollateral(borrower, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), borrower, nil))
+	_, _, err = morpho.Borrow(borrower, marketParams, uint256.MustFromDecimal("500000000000000000000"), uint256.NewInt(0), borrower, borrower)
+	require.NoError(t, err)
+
+	baseMarket, _ := morpho.Market.Get(marketId)
+	baseRateAtTarget, _ := irm.RateAtTarget.Get(marketId)
+	basePosition, _ := morpho.getPosition(marketId, borrower)
+	baseLogs := len(morpho.Logs)
+
+	var wg sync.WaitGroup
+	markets := make([]Market, 8)
+	for i := range markets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fork := morpho.Fork()
+			fork.BlockTimestamp += uint64(i+1) * 86400
+			_, _, err := fork.Borrow(borrower, marketParams, uint256.MustFromDecimal("100000000000000000000"), uint256.NewInt(0), borrower, borrower)
+			assert.NoError(t, err)
+			assert.NoError(t, fork.SetFeeRecipient(owner, supplier))
+			markets[i], _ = fork.Market.Get(marketId)
+
+			forkIrm, _ := fork.Irms.Get(irmAddr)
+			rateAtTarget, _ := forkIrm.(*AdaptiveCurveIrm).RateAtTarget.Get(marketId)
+			assert.False(t, rateAtTarget.Eq(&baseRateAtTarget))
+			assert.Len(t, fork.Logs, 3)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < len(markets); i++ {
+		require.True(t, markets[i].TotalBorrowAssets.Gt(&markets[i-1].TotalBorrowAssets))
+	}
+
+	market, _ := morpho.Market.Get(marketId)
+	require.Equal(t, baseMarket, market)
+	rateAtTarget, _ := irm.RateAtTarget.Get(marketId)
+	require.Equal(t, baseRateAtTarget, rateAtTarget)
+	position, _ := morpho.getPosition(marketId, borrower)
+	require.Equal(t, basePosition, position)
+	balance, _ := morpho.BalanceOf(marketParams.LoanToken, borrower)
+	require.Equal(t, "500000000000000000000", balance.String())
+	require.Equal(t, owner, morpho.FeeRecipient)
+	require.Len(t, morpho.Logs, baseLogs)
+
+	// a failed operation in a fork reverts within the fork
+	fork := morpho.Fork()
+	_, _, err = fork.Borrow(borrower, marketParams, uint256.MustFromDecimal("400000000000000000000"), uint256.NewInt(0), borrower, borrower)
+	require.Equal(t, ErrorInsufficientCollateral, err)
+	position, _ = fork.getPosition(marketId, borrower)
+	require.Equal(t, basePosition, position)
+}
+
+func TestConcurrentReads(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	morpho := NewMorpho(owner, owner, WithMappingFactory(gosol.MappingFactory{Kind: gosol.ShardedKind}))
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	marketId := ComputeMarketId(marketParams)
+	irm := NewAdaptiveCurveIrm(morpho)
+	require.NoError(t, morpho.Irms.Set(irmAddr, irm))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("100000000000000000000")))
+
+	// operations are applied by a single goroutine while others read the state
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// the IRM is read on its own, so that the locks of the other mappings do not order its accesses
+				if i%2 == 1 {
+					_, err := irm.RateAtTarget.Get(marketId)
+					assert.NoError(t, err)
+					continue
+				}
+				_, err := morpho.Market.Get(marketId)
+				assert.NoError(t, err)
+				_, err = morpho.getPosition(marketId, supplier)
+				assert.NoError(t, err)
+				_, err = morpho.PositionUsers(marketId)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	for i := 0; i < 100; i++ {
+		// the interest accrual updates the rate at target of the IRM
+		morpho.BlockTimestamp++
+		_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000"), uint256.NewInt(0), supplier, nil)
+		require.NoError(t, err)
+	}
+	close(done)
+	wg.Wait()
+
+	market, _ := morpho.Market.Get(marketId)
+	require.Equal(t, "100000000000000000000", market.TotalSupplyAssets.String())
+}
+
+// countingOracle is a stateful oracle counting its calls
+type countingOracle struct {
+	price *uint256.Int
+	calls int
+}
+
+func (o *countingOracle) Price() (*uint256.Int, error) {
+	o.calls++
+	return o.price, nil
+}
+
+func (o *countingOracle) Fork(morpho *Morpho) Oracle {
+	return &countingOracle{price: o.price, calls: o.calls}
+}
+
+// countingCallback is a stateful callback contract counting its calls
+type countingCallback struct {
+	calls int
+}
+
+func (c *countingCallback) OnMorphoSupply(assets *uint256.Int, data []byte) error {
+	c.calls++
+	return nil
+}
+
+func (c *countingCallback) Fork(morpho *Morpho) any {
+	return &countingCallback{calls: c.calls}
+}
+
+func TestForkOraclesAndCallbacks(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	oracleAddr := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	morpho := NewMorpho(owner, owner)
+	oracle := &countingOracle{price: ORACLE_PRICE_SCALE}
+	callback := &countingCallback{}
+	require.NoError(t, morpho.Oracles.Set(oracleAddr, oracle))
+	require.NoError(t, morpho.Callbacks.Set(owner, callback))
+
+	// forks of forks are read concurrently
+	parent := morpho.Fork()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fork := parent.Fork()
+			forkOracle, err := fork.getOracle(oracleAddr)
+			assert.NoError(t, err)
+			_, err = forkOracle.Price()
+			assert.NoError(t, err)
+			forkCallback, err := getCallback[MorphoSupplyCallback](fork, owner)
+			assert.NoError(t, err)
+			assert.NoError(t, forkCallback.OnMorphoSupply(uint256.NewInt(1), nil))
+			assert.NotSame(t, oracle, forkOracle)
+		}()
+	}
+	wg.Wait()
+
+	require.Zero(t, oracle.calls)
+	require.Zero(t, callback.calls)
+	parentOracle, err := parent.getOracle(oracleAddr)
+	require.NoError(t, err)
+	require.Zero(t, parentOracle.(*countingOracle).calls)
+}
@@ -23,6 +23,7 @@ func TestIsHealthy(t *testing.T) {
 	}
 	
 	// Enable IRM and LLTV
+	morpho.Irms.Set(marketParams.Irm, &mockIRM{rate: uint256.NewInt(0)})
 	morpho.EnableIrm(owner, marketParams.Irm)
 	morpho.EnableLltv(owner, &marketParams.Lltv)
 	
@@ -58,7 +59,7 @@ func TestIsHealthy(t *testing.T) {
 				BorrowShares: *uint256.NewInt(0),
 				Collateral:   *uint256.MustFromDecimal("100000000000000000000"),
 			},
-			collateralPrice: WAD, // 1:1 price
+			collateralPrice: ORACLE_PRICE_SCALE, // 1:1 price
 			expectedHealthy: true,
 		},
 		{
@@ -69,7 +70,7 @@ func TestIsHealthy(t *testing.T) {
 				BorrowShares: *uint256.MustFromDecimal("100000000000000000000"), // 100 tokens borrowed
 				Collateral:   *uint256.MustFromDecimal("250000000000000000000"), // 250 collateral
 			},
-			collateralPrice: WAD, // 1:1 price
+			collateralPrice: ORACLE_PRICE_SCALE, // 1:1 price
 			expectedHealthy: true, // 100 borrowed < 250 * 0.8 = 200 max borrowable
 		},
 		{
@@ -80,7 +81,7 @@ func TestIsHealthy(t *testing.T) {
 				BorrowShares: *uint256.MustFromDecimal("100000000000000000000"), // 100 tokens borrowed
 				Collateral:   *uint256.MustFromDecimal("111111111111111111111"), // 111.11 collateral
 			},
-			collateralPrice: WAD, // 1:1 price
+			collateralPrice: ORACLE_PRICE_SCALE, // 1:1 price
 			expectedHealthy: false, // 100 borrowed > 111.11 * 0.8 = 88.88 max borrowable
 		},
 		{
@@ -91,7 +92,7 @@ func TestIsHealthy(t *testing.T) {
 				BorrowShares: *uint256.MustFromDecimal("100000000000000000000"), // 100 tokens borrowed
 				Collateral:   *uint256.MustFromDecimal("50000000000000000000"),  // 50 collateral
 			},
-			collateralPrice: uint256.MustFromDecimal("3000000000000000000"), // 3:1 price
+			collateralPrice: uint256.MustFromDecimal("3000000000000000000000000000000000000"), // 3:1 price
 			expectedHealthy: true, // 100 borrowed < 50 * 3 * 0.8 = 120 max borrowable
 		},
 		{
@@ -102,7 +103,7 @@ func TestIsHealthy(t *testing.T) {
 				BorrowShares: *uint256.MustFromDecimal("100000000000000000000"), // 100 tokens borrowed
 				Collateral:   *uint256.MustFromDecimal("200000000000000000000"), // 200 collateral
 			},
-			collateralPrice: uint256.MustFromDecimal("500000000000000000"), // 0.5:1 price
+			collateralPrice: uint256.MustFromDecimal("500000000000000000000000000000000000"), // 0.5:1 price
 			expectedHealthy: false, // 100 borrowed > 200 * 0.5 * 0.8 = 80 max borrowable
 		},
 		{
@@ -113,7 +114,7 @@ func TestIsHealthy(t *testing.T) {
 				BorrowShares: *uint256.MustFromDecimal("80000000000000000000"), // 80 tokens borrowed
 				Collateral:   *uint256.MustFromDecimal("100000000000000000000"), // 100 collateral
 			},
-			collateralPrice: WAD, // 1:1 price
+			collateralPrice: ORACLE_PRICE_SCALE, // 1:1 price
 			expectedHealthy: true, // 80 borrowed = 100 * 0.8 = 80 max borrowable (exactly at limit)
 		},
 	}
@@ -124,7 +125,8 @@ func TestIsHealthy(t *testing.T) {
 			morpho.setPosition(marketId, tc.user, tc.position)
 			
 			// Test the IsHealthy method
-			healthy := morpho.IsHealthy(marketParams, marketId, tc.user, tc.collateralPrice)
+			healthy, err := morpho.IsHealthy(marketParams, marketId, tc.user, tc.collateralPrice)
+			require.NoError(t, err)
 			require.Equal(t, tc.expectedHealthy, healthy, "Health check mismatch")
 		})
 	}
@@ -137,6 +139,8 @@ func TestCheckHealthIntegration(t *testing.T) {
 	
 	// Enable IRM and LLTV
 	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	borrowRate := uint256.MustFromDecimal("50000000000000000") // 5%
+	morpho.Irms.Set(irmAddr, &mockIRM{rate: borrowRate})
 	err := morpho.EnableIrm(owner, irmAddr)
 	require.NoError(t, err)
 	
@@ -155,18 +159,22 @@ func TestCheckHealthIntegration(t *testing.T) {
 	
 	err = morpho.CreateMarket(owner, marketParams)
 	require.NoError(t, err)
+
+	oracle := &mockOracle{}
+	morpho.Oracles.Set(marketParams.Oracle, oracle)
 	
 	// Supply liquidity
 	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
 	supplyAmount := uint256.MustFromDecimal("1000000000000000000000") // 1000 tokens
-	borrowRate := uint256.MustFromDecimal("50000000000000000") // 5%
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, supplyAmount))
 	
-	_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil, borrowRate)
+	_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
 	require.NoError(t, err)
 	
 	// Supply collateral
 	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
 	collateralAmount := uint256.MustFromDecimal("100000000000000000000") // 100 collateral
+	require.NoError(t, morpho.Deal(marketParams.CollateralToken, borrower, collateralAmount))
 	
 	err = morpho.SupplyCollateral(borrower, uint256.NewInt(0), marketParams, collateralAmount, borrower, nil)
 	require.NoError(t, err)
@@ -174,19 +182,135 @@ func TestCheckHealthIntegration(t *testing.T) {
 	// Test borrowing with different collateral prices
 	t.Run("Borrow succeeds with high collateral price", func(t *testing.T) {
 		borrowAmount := uint256.MustFromDecimal("50000000000000000000") // 50 tokens
-		collateralPrice := uint256.MustFromDecimal("2000000000000000000") // 2:1 price
+		oracle.price = uint256.MustFromDecimal("2000000000000000000000000000000000000") // 2:1 price
 		
-		_, _, err := morpho.Borrow(borrower, marketParams, borrowAmount, uint256.NewInt(0), borrower, borrower, borrowRate, collateralPrice)
+		_, _, err := morpho.Borrow(borrower, marketParams, borrowAmount, uint256.NewInt(0), borrower, borrower)
 		require.NoError(t, err)
 	})
 	
 	// Test withdrawing collateral with health check
 	t.Run("Withdraw collateral fails when it would make position unhealthy", func(t *testing.T) {
 		withdrawAmount := uint256.MustFromDecimal("90000000000000000000") // Try to withdraw 90 collateral
-		collateralPrice := WAD // 1:1 price
+		oracle.price = ORACLE_PRICE_SCALE // 1:1 price
 		
-		err := morpho.WithdrawCollateral(borrower, marketParams, withdrawAmount, borrower, borrower, borrowRate, collateralPrice)
+		err := morpho.WithdrawCollateral(borrower, marketParams, withdrawAmount, borrower, borrower)
 		require.Error(t, err)
 		require.Equal(t, ErrorInsufficientCollateral, err)
 	})
-}
\ No newline at end of file
+}
+
+func TestLiquidate(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	liquidator := common.HexToAddress("0x7777777777777777777777777777777777777777")
+
+	lltv := uint256.MustFromDecimal("800000000000000000") // 80%
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *lltv,
+	}
+	marketId := ComputeMarketId(marketParams)
+
+	// supplies 1000 tokens, and borrows 80 tokens against 100 collateral at a 1:1 price
+	setup := func(t *testing.T) (*Morpho, *mockOracle) {
+		morpho := NewMorpho(owner, owner)
+		oracle := &mockOracle{price: ORACLE_PRICE_SCALE}
+		require.NoError(t, morpho.Oracles.Set(marketParams.Oracle, oracle))
+		require.NoError(t, morpho.Irms.Set(marketParams.Irm, &mockIRM{rate: uint256.NewInt(0)}))
+		require.NoError(t, morpho.EnableIrm(owner, marketParams.Irm))
+		require.NoError(t, morpho.EnableLltv(owner, lltv))
+		require.NoError(t, morpho.CreateMarket(owner, marketParams))
+		require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+		require.NoError(t, morpho.Deal(marketParams.CollateralToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+		require.NoError(t, morpho.Deal(marketParams.LoanToken, liquidator, uint256.MustFromDecimal("100000000000000000000")))
+		_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+		require.NoError(t, err)
+		err = morpho.SupplyCollateral(borrower, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("100000000000000000000"), borrower, nil)
+		require.NoError(t, err)
+		_, _, err = morpho.Borrow(borrower, marketParams, uint256.MustFromDecimal("80000000000000000000"), uint256.NewInt(0), borrower, borrower)
+		require.NoError(t, err)
+		return morpho, oracle
+	}
+
+	t.Run("Liquidation incentive factor", func(t *testing.T) {
+		lif, err := LiquidationIncentiveFactor(lltv)
+		require.NoError(t, err)
+		require.Equal(t, "1063829787234042553", lif.String())
+
+		lif, err = LiquidationIncentiveFactor(uint256.NewInt(0))
+		require.NoError(t, err)
+		require.Equal(t, MAX_LIQUIDATION_INCENTIVE_FACTOR.String(), lif.String())
+	})
+
+	t.Run("Liquidator without funds cannot repay", func(t *testing.T) {
+		morpho, oracle := setup(t)
+		oracle.price = uint256.MustFromDecimal("900000000000000000000000000000000000") // 0.9:1 price
+		require.NoError(t, morpho.Deal(marketParams.LoanToken, liquidator, uint256.NewInt(0)))
+		_, _, err := morpho.Liquidate(liquidator, uint256.NewInt(0), marketParams, borrower, uint256.NewInt(0), uint256.MustFromDecimal("40000000000000000000000000"), nil)
+		require.Equal(t, ErrorTransferFromReverted, err)
+	})
+
+	t.Run("Healthy position cannot be liquidated", func(t *testing.T) {
+		morpho, _ := setup(t)
+		_, _, err := morpho.Liquidate(liquidator, uint256.NewInt(0), marketParams, borrower, uint256.NewInt(1), uint256.NewInt(0), nil)
+		require.Equal(t, ErrorHealthyPosition, err)
+	})
+
+	t.Run("Overflowing health check reverts instead of liquidating", func(t *testing.T) {
+		morpho, _ := setup(t)
+		market, _ := morpho.Market.Get(marketId)
+		market.TotalBorrowAssets = MaxUint256
+		require.NoError(t, morpho.Market.Set(marketId, market))
+		_, err := morpho.IsHealthy(marketParams, marketId, borrower, ORACLE_PRICE_SCALE)
+		require.ErrorIs(t, err, ErrorUint256Overflow)
+		_, _, err = morpho.Liquidate(liquidator, uint256.NewInt(0), marketParams, borrower, uint256.NewInt(1), uint256.NewInt(0), nil)
+		require.ErrorIs(t, err, ErrorUint256Overflow)
+		position, _ := morpho.getPosition(marketId, borrower)
+		require.Equal(t, "100000000000000000000", position.Collateral.String())
+	})
+
+	t.Run("Partial liquidation by repaid shares", func(t *testing.T) {
+		morpho, oracle := setup(t)
+		oracle.price = uint256.MustFromDecimal("900000000000000000000000000000000000") // 0.9:1 price
+		seized, repaid, err := morpho.Liquidate(liquidator, uint256.NewInt(0), marketParams, borrower, uint256.NewInt(0), uint256.MustFromDecimal("40000000000000000000000000"), nil)
+		require.NoError(t, err)
+		require.Equal(t, "47281323877068557911", seized.String())
+		require.Equal(t, "40000000000000000000", repaid.String())
+
+		market, _ := morpho.Market.Get(marketId)
+		require.Equal(t, "40000000000000000000", market.TotalBorrowAssets.String())
+		require.Equal(t, "40000000000000000000000000", market.TotalBorrowShares.String())
+		require.Equal(t, "1000000000000000000000", market.TotalSupplyAssets.String())
+
+		position, _ := morpho.getPosition(marketId, borrower)
+		require.Equal(t, "52718676122931442089", position.Collateral.String())
+		require.Equal(t, "40000000000000000000000000", position.BorrowShares.String())
+
+		balance, _ := morpho.BalanceOf(marketParams.CollateralToken, liquidator)
+		require.Equal(t, seized.String(), balance.String())
+		balance, _ = morpho.BalanceOf(marketParams.LoanToken, liquidator)
+		require.Equal(t, "60000000000000000000", balance.String())
+	})
+
+	t.Run("Seizing all collateral realizes bad debt", func(t *testing.T) {
+		morpho, oracle := setup(t)
+		oracle.price = uint256.MustFromDecimal("500000000000000000000000000000000000") // 0.5:1 price
+		seized, repaid, err := morpho.Liquidate(liquidator, uint256.NewInt(0), marketParams, borrower, uint256.MustFromDecimal("100000000000000000000"), uint256.NewInt(0), nil)
+		require.NoError(t, err)
+		require.Equal(t, "100000000000000000000", seized.String())
+		require.Equal(t, "47000000000000000009", repaid.String())
+
+		market, _ := morpho.Market.Get(marketId)
+		require.True(t, market.TotalBorrowAssets.IsZero())
+		require.True(t, market.TotalBorrowShares.IsZero())
+		require.Equal(t, "967000000000000000009", market.TotalSupplyAssets.String())
+
+		position, _ := morpho.getPosition(marketId, borrower)
+		require.True(t, position.Collateral.IsZero())
+		require.True(t, position.BorrowShares.IsZero())
+	})
+}
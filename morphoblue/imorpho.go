@@ -27,3 +27,17 @@ type MarketParams struct {
 	Irm             common.Address
 	Lltv            uint256.Int
 }
+
+type Authorization struct {
+	Authorizer   common.Address
+	Authorized   common.Address
+	IsAuthorized bool
+	Nonce        uint256.Int
+	Deadline     uint256.Int
+}
+
+type Signature struct {
+	V uint8
+	R common.Hash
+	S common.Hash
+}
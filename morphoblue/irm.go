@@ -0,0 +1,12 @@
+package morphoblue
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// IRM is the interface of an interest rate model, based on IIrm.sol
+// https://github.com/morpho-org/morpho-blue/blob/main/src/interfaces/IIrm.sol
+type IRM interface {
+	// BorrowRate returns the borrow rate per second (scaled by WAD) of the market
+	BorrowRate(marketParams MarketParams, market Market) (*uint256.Int, error)
+}
@@ -0,0 +1,252 @@
+package morphoblue
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// MarketEvent is an event about a single market
+type MarketEvent interface {
+	Event
+	MarketId() common.Hash
+}
+
+func (e SetFeeEvent) MarketId() common.Hash             { return e.Id }
+func (e CreateMarketEvent) MarketId() common.Hash       { return e.Id }
+func (e SupplyEvent) MarketId() common.Hash             { return e.Id }
+func (e WithdrawEvent) MarketId() common.Hash           { return e.Id }
+func (e BorrowEvent) MarketId() common.Hash             { return e.Id }
+func (e RepayEvent) MarketId() common.Hash              { return e.Id }
+func (e SupplyCollateralEvent) MarketId() common.Hash   { return e.Id }
+func (e WithdrawCollateralEvent) MarketId() common.Hash { return e.Id }
+func (e LiquidateEvent) MarketId() common.Hash          { return e.Id }
+func (e AccrueInterestEvent) MarketId() common.Hash     { return e.Id }
+
+// eventDecoders build the events from their indexed and non indexed arguments, by name
+var eventDecoders = map[string]func(args map[string]any) Event{
+	"SetOwner": func(args map[string]any) Event {
+		return SetOwnerEvent{NewOwner: args["newOwner"].(common.Address)}
+	},
+	"SetFee": func(args map[string]any) Event {
+		return SetFeeEvent{Id: args["id"].([32]byte), NewFee: fromABIUint(args["newFee"])}
+	},
+	"SetFeeRecipient": func(args map[string]any) Event {
+		return SetFeeRecipientEvent{NewFeeRecipient: args["newFeeRecipient"].(common.Address)}
+	},
+	"EnableIrm": func(args map[string]any) Event {
+		return EnableIrmEvent{Irm: args["irm"].(common.Address)}
+	},
+	"EnableLltv": func(args map[string]any) Event {
+		return EnableLltvEvent{Lltv: fromABIUint(args["lltv"])}
+	},
+	"CreateMarket": func(args map[string]any) Event {
+		return CreateMarketEvent{Id: args["id"].([32]byte), MarketParams: fromABIMarketParams(args["marketParams"])}
+	},
+	"Supply": func(args map[string]any) Event {
+		return SupplyEvent{
+			Id:       args["id"].([32]byte),
+			Caller:   args["caller"].(common.Address),
+			OnBehalf: args["onBehalf"].(common.Address),
+			Assets:   fromABIUint(args["assets"]),
+			Shares:   fromABIUint(args["shares"]),
+		}
+	},
+	"Withdraw": func(args map[string]any) Event {
+		return WithdrawEvent{
+			Id:       args["id"].([32]byte),
+			Caller:   args["caller"].(common.Address),
+			OnBehalf: args["onBehalf"].(common.Address),
+			Receiver: args["receiver"].(common.Address),
+			Assets:   fromABIUint(args["assets"]),
+			Shares:   fromABIUint(args["shares"]),
+		}
+	},
+	"Borrow": func(args map[string]any) Event {
+		return BorrowEvent{
+			Id:       args["id"].([32]byte),
+			Caller:   args["caller"].(common.Address),
+			OnBehalf: args["onBehalf"].(common.Address),
+			Receiver: args["receiver"].(common.Address),
+			Assets:   fromABIUint(args["assets"]),
+			Shares:   fromABIUint(args["shares"]),
+		}
+	},
+	"Repay": func(args map[string]any) Event {
+		return RepayEvent{
+			Id:       args["id"].([32]byte),
+			Caller:   args["caller"].(common.Address),
+			OnBehalf: args["onBehalf"].(common.Address),
+			Assets:   fromABIUint(args["assets"]),
+			Shares:   fromABIUint(args["shares"]),
+		}
+	},
+	"SupplyCollateral": func(args map[string]any) Event {
+		return SupplyCollateralEvent{
+			Id:       args["id"].([32]byte),
+			Caller:   args["caller"].(common.Address),
+			OnBehalf: args["onBehalf"].(common.Address),
+			Assets:   fromABIUint(args["assets"]),
+		}
+	},
+	"WithdrawCollateral": func(args map[string]any) Event {
+		return WithdrawCollateralEvent{
+			Id:       args["id"].([32]byte),
+			Caller:   args["caller"].(common.Address),
+			OnBehalf: args["onBehalf"].(common.Address),
+			Receiver: args["receiver"].(common.Address),
+			Assets:   fromABIUint(args["assets"]),
+		}
+	},
+	"Liquidate": func(args map[string]any) Event {
+		return LiquidateEvent{
+			Id:            args["id"].([32]byte),
+			Caller:        args["caller"].(common.Address),
+			Borrower:      args["borrower"].(common.Address),
+			RepaidAssets:  fromABIUint(args["repaidAssets"]),
+			RepaidShares:  fromABIUint(args["repaidShares"]),
+			SeizedAssets:  fromABIUint(args["seizedAssets"]),
+			BadDebtAssets: fromABIUint(args["badDebtAssets"]),
+			BadDebtShares: fromABIUint(args["badDebtShares"]),
+		}
+	},
+	"FlashLoan": func(args map[string]any) Event {
+		return FlashLoanEvent{
+			Caller: args["caller"].(common.Address),
+			Token:  args["token"].(common.Address),
+			Assets: fromABIUint(args["assets"]),
+		}
+	},
+	"SetAuthorization": func(args map[string]any) Event {
+		return SetAuthorizationEvent{
+			Caller:          args["caller"].(common.Address),
+			Authorizer:      args["authorizer"].(common.Address),
+			Authorized:      args["authorized"].(common.Address),
+			NewIsAuthorized: args["newIsAuthorized"].(bool),
+		}
+	},
+	"IncrementNonce": func(args map[string]any) Event {
+		return IncrementNonceEvent{
+			Caller:     args["caller"].(common.Address),
+			Authorizer: args["authorizer"].(common.Address),
+			UsedNonce:  fromABIUint(args["usedNonce"]),
+		}
+	},
+	"AccrueInterest": func(args map[string]any) Event {
+		return AccrueInterestEvent{
+			Id:             args["id"].([32]byte),
+			PrevBorrowRate: fromABIUint(args["prevBorrowRate"]),
+			Interest:       fromABIUint(args["interest"]),
+			FeeShares:      fromABIUint(args["feeShares"]),
+		}
+	},
+}
+
+// DecodeLog returns the event emitted by Morpho Blue in log, events about a market implement MarketEvent.
+// it fails with ErrorUnknownEvent if the log is not a Morpho Blue event, and with ErrorMalformedLog if its topics
+// do not match the event. the address of the log is not checked
+func DecodeLog(log types.Log) (Event, error) {
+	if len(log.Topics) == 0 {
+		return nil, ErrorUnknownEvent
+	}
+	event, err := MorphoBlueABI.EventByID(log.Topics[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownEvent, log.Topics[0])
+	}
+	decode, ok := eventDecoders[event.Name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownEvent, event.Name)
+	}
+
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if len(log.Topics) != len(indexed)+1 {
+		return nil, fmt.Errorf("%s: %w", event.Name, ErrorMalformedLog)
+	}
+	args := map[string]any{}
+	if err := event.Inputs.UnpackIntoMap(args, log.Data); err != nil {
+		return nil, fmt.Errorf("%s: %w", event.Name, err)
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, log.Topics[1:]); err != nil {
+		return nil, fmt.Errorf("%s: %w", event.Name, err)
+	}
+	return decode(args), nil
+}
+
+// eventArgs returns the arguments of the event by name, as packed by the abi package
+func eventArgs(event Event) map[string]any {
+	switch e := event.(type) {
+	case SetOwnerEvent:
+		return map[string]any{"newOwner": e.NewOwner}
+	case SetFeeEvent:
+		return map[string]any{"id": e.Id, "newFee": e.NewFee.ToBig()}
+	case SetFeeRecipientEvent:
+		return map[string]any{"newFeeRecipient": e.NewFeeRecipient}
+	case EnableIrmEvent:
+		return map[string]any{"irm": e.Irm}
+	case EnableLltvEvent:
+		return map[string]any{"lltv": e.Lltv.ToBig()}
+	case CreateMarketEvent:
+		return map[string]any{"id": e.Id, "marketParams": toABIMarketParams(e.MarketParams)}
+	case SupplyEvent:
+		return map[string]any{"id": e.Id, "caller": e.Caller, "onBehalf": e.OnBehalf, "assets": e.Assets.ToBig(), "shares": e.Shares.ToBig()}
+	case WithdrawEvent:
+		return map[string]any{"id": e.Id, "caller": e.Caller, "onBehalf": e.OnBehalf, "receiver": e.Receiver, "assets": e.Assets.ToBig(), "shares": e.Shares.ToBig()}
+	case BorrowEvent:
+		return map[string]any{"id": e.Id, "caller": e.Caller, "onBehalf": e.OnBehalf, "receiver": e.Receiver, "assets": e.Assets.ToBig(), "shares": e.Shares.ToBig()}
+	case RepayEvent:
+		return map[string]any{"id": e.Id, "caller": e.Caller, "onBehalf": e.OnBehalf, "assets": e.Assets.ToBig(), "shares": e.Shares.ToBig()}
+	case SupplyCollateralEvent:
+		return map[string]any{"id": e.Id, "caller": e.Caller, "onBehalf": e.OnBehalf, "assets": e.Assets.ToBig()}
+	case WithdrawCollateralEvent:
+		return map[string]any{"id": e.Id, "caller": e.Caller, "onBehalf": e.OnBehalf, "receiver": e.Receiver, "assets": e.Assets.ToBig()}
+	case LiquidateEvent:
+		return map[string]any{
+			"id": e.Id, "caller": e.Caller, "borrower": e.Borrower,
+			"repaidAssets": e.RepaidAssets.ToBig(), "repaidShares": e.RepaidShares.ToBig(), "seizedAssets": e.SeizedAssets.ToBig(),
+			"badDebtAssets": e.BadDebtAssets.ToBig(), "badDebtShares": e.BadDebtShares.ToBig(),
+		}
+	case FlashLoanEvent:
+		return map[string]any{"caller": e.Caller, "token": e.Token, "assets": e.Assets.ToBig()}
+	case SetAuthorizationEvent:
+		return map[string]any{"caller": e.Caller, "authorizer": e.Authorizer, "authorized": e.Authorized, "newIsAuthorized": e.NewIsAuthorized}
+	case IncrementNonceEvent:
+		return map[string]any{"caller": e.Caller, "authorizer": e.Authorizer, "usedNonce": e.UsedNonce.ToBig()}
+	case AccrueInterestEvent:
+		return map[string]any{"id": e.Id, "prevBorrowRate": e.PrevBorrowRate.ToBig(), "interest": e.Interest.ToBig(), "feeShares": e.FeeShares.ToBig()}
+	}
+	return nil
+}
+
+// EncodeLog returns the log emitted by the Morpho Blue deployed at address for event, the reverse of DecodeLog
+func EncodeLog(address common.Address, event Event) (types.Log, error) {
+	abiEvent, ok := MorphoBlueABI.Events[event.EventName()]
+	args := eventArgs(event)
+	if !ok || args == nil {
+		return types.Log{}, fmt.Errorf("%w: %s", ErrorUnknownEvent, event.EventName())
+	}
+	topics := []common.Hash{abiEvent.ID}
+	var data []any
+	for _, input := range abiEvent.Inputs {
+		if !input.Indexed {
+			data = append(data, args[input.Name])
+			continue
+		}
+		topic, err := abi.MakeTopics([]any{args[input.Name]})
+		if err != nil {
+			return types.Log{}, fmt.Errorf("%s: %w", abiEvent.Name, err)
+		}
+		topics = append(topics, topic[0][0])
+	}
+	packed, err := abiEvent.Inputs.NonIndexed().Pack(data...)
+	if err != nil {
+		return types.Log{}, fmt.Errorf("%s: %w", abiEvent.Name, err)
+	}
+	return types.Log{Address: address, Topics: topics, Data: packed}, nil
+}
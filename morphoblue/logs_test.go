@@ -0,0 +1,100 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLog(t *testing.T) {
+	morphoAddress := common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb")
+	id := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	caller := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	onBehalf := common.HexToAddress("0x6666666666666666666666666666666666666666")
+
+	// Supply(bytes32 indexed id, address indexed caller, address indexed onBehalf, uint256 assets, uint256 shares)
+	log := types.Log{
+		Address: morphoAddress,
+		Topics: []common.Hash{
+			common.HexToHash("0xedf8870433c83823eb071d3df1caa8d008f12f6440918c20d75a3602cda30fe0"),
+			id,
+			common.BytesToHash(caller.Bytes()),
+			common.BytesToHash(onBehalf.Bytes()),
+		},
+		Data: hexutil.MustDecode("0x" +
+			"0000000000000000000000000000000000000000000000000de0b6b3a7640000" +
+			"00000000000000000000000000000000000000000000000000000000000f4240"),
+	}
+	event, err := DecodeLog(log)
+	require.NoError(t, err)
+	require.Equal(t, SupplyEvent{
+		Id:       id,
+		Caller:   caller,
+		OnBehalf: onBehalf,
+		Assets:   *uint256.NewInt(1000000000000000000),
+		Shares:   *uint256.NewInt(1000000),
+	}, event)
+	require.Equal(t, id, event.(MarketEvent).MarketId())
+
+	encoded, err := EncodeLog(morphoAddress, event)
+	require.NoError(t, err)
+	require.Equal(t, log, encoded)
+
+	// a topic is missing
+	log.Topics = log.Topics[:3]
+	_, err = DecodeLog(log)
+	require.ErrorIs(t, err, ErrorMalformedLog)
+	require.NotErrorIs(t, err, ErrorInconsistentInput)
+
+	_, err = DecodeLog(types.Log{Topics: []common.Hash{id}})
+	require.ErrorIs(t, err, ErrorUnknownEvent)
+	_, err = DecodeLog(types.Log{})
+	require.ErrorIs(t, err, ErrorUnknownEvent)
+}
+
+func TestDecodeLogEvents(t *testing.T) {
+	id := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	a := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	b := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	c := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	x, y, z := *uint256.NewInt(1), *uint256.NewInt(2), *uint256.NewInt(3)
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *uint256.MustFromDecimal("800000000000000000"),
+	}
+
+	for _, event := range []Event{
+		SetOwnerEvent{NewOwner: a},
+		SetFeeEvent{Id: id, NewFee: x},
+		SetFeeRecipientEvent{NewFeeRecipient: a},
+		EnableIrmEvent{Irm: a},
+		EnableLltvEvent{Lltv: x},
+		CreateMarketEvent{Id: id, MarketParams: marketParams},
+		SupplyEvent{Id: id, Caller: a, OnBehalf: b, Assets: x, Shares: y},
+		WithdrawEvent{Id: id, Caller: a, OnBehalf: b, Receiver: c, Assets: x, Shares: y},
+		BorrowEvent{Id: id, Caller: a, OnBehalf: b, Receiver: c, Assets: x, Shares: y},
+		RepayEvent{Id: id, Caller: a, OnBehalf: b, Assets: x, Shares: y},
+		SupplyCollateralEvent{Id: id, Caller: a, OnBehalf: b, Assets: x},
+		WithdrawCollateralEvent{Id: id, Caller: a, OnBehalf: b, Receiver: c, Assets: x},
+		LiquidateEvent{Id: id, Caller: a, Borrower: b, RepaidAssets: x, RepaidShares: y, SeizedAssets: z, BadDebtAssets: x, BadDebtShares: y},
+		FlashLoanEvent{Caller: a, Token: b, Assets: x},
+		SetAuthorizationEvent{Caller: a, Authorizer: b, Authorized: c, NewIsAuthorized: true},
+		IncrementNonceEvent{Caller: a, Authorizer: b, UsedNonce: x},
+		AccrueInterestEvent{Id: id, PrevBorrowRate: x, Interest: y, FeeShares: z},
+	} {
+		t.Run(event.EventName(), func(t *testing.T) {
+			log, err := EncodeLog(common.Address{}, event)
+			require.NoError(t, err)
+			decoded, err := DecodeLog(log)
+			require.NoError(t, err)
+			require.Equal(t, event, decoded)
+		})
+	}
+}
@@ -16,6 +16,21 @@ func WadMulUp(z *uint256.Int, x *uint256.Int, y *uint256.Int) (*uint256.Int, err
 	return z, err
 }
 
+// WadMulDown returns (x * y) / WAD rounded down
+func WadMulDown(z *uint256.Int, x *uint256.Int, y *uint256.Int) (*uint256.Int, error) {
+	return MulDiv(z, x, y, WAD)
+}
+
+// WadDivDown returns (x * WAD) / y rounded down
+func WadDivDown(z *uint256.Int, x *uint256.Int, y *uint256.Int) (*uint256.Int, error) {
+	return MulDiv(z, x, WAD, y)
+}
+
+// WadDivUp returns (x * WAD) / y rounded up
+func WadDivUp(z *uint256.Int, x *uint256.Int, y *uint256.Int) (*uint256.Int, error) {
+	return MulDivRoundingUp(z, x, WAD, y)
+}
+
 // WadMulToZero computes x * y / WAD, using signed arithmetic
 func WadMulToZero(z *uint256.Int, x *uint256.Int, y *uint256.Int) *uint256.Int {
 	z.Mul(x, y)
@@ -23,11 +38,14 @@ func WadMulToZero(z *uint256.Int, x *uint256.Int, y *uint256.Int) *uint256.Int {
 	return z
 }
 
+// MulDiv returns (a * b) / denominator rounded down, failing like Solidity reverts if the result overflows
 func MulDiv(z *uint256.Int, a, b, denominator *uint256.Int) (*uint256.Int, error) {
 	if denominator.IsZero() {
 		return nil, ErrorDivideByZero
 	}
-	z.MulDivOverflow(a, b, denominator)
+	if _, overflow := z.MulDivOverflow(a, b, denominator); overflow {
+		return nil, ErrorUint256Overflow
+	}
 	return z, nil
 }
 
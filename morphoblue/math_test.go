@@ -159,3 +159,17 @@ func TestMulDivRoundingUp(t *testing.T) {
 		})
 	}
 }
+
+func TestMulDiv(t *testing.T) {
+	result, err := MulDiv(new(uint256.Int), &MaxUint256, WAD, WAD)
+	require.NoError(t, err)
+	require.Equal(t, MaxUint256.String(), result.String())
+
+	// the result does not fit in 256 bits
+	_, err = MulDiv(new(uint256.Int), &MaxUint256, uint256.NewInt(2), uint256.NewInt(1))
+	require.ErrorIs(t, err, ErrorUint256Overflow)
+	_, err = WadMulDown(new(uint256.Int), &MaxUint256, uint256.MustFromDecimal("2000000000000000000"))
+	require.ErrorIs(t, err, ErrorUint256Overflow)
+	_, err = MulDivRoundingUp(new(uint256.Int), &MaxUint256, uint256.NewInt(3), uint256.NewInt(2))
+	require.ErrorIs(t, err, ErrorUint256Overflow)
+}
@@ -1,6 +1,9 @@
 package morphoblue
 
 import (
+	"errors"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gfx-labs/go-blue-sdk/gosol"
 	"github.com/holiman/uint256"
@@ -17,26 +20,1278 @@ type Morpho struct {
 	Market   gosol.Mapping[common.Hash, Market]
 
 	IsIrmEnabled  gosol.Mapping[common.Address, bool]
-	IsLltvEnabled gosol.Mapping[common.Address, bool]
+	IsLltvEnabled gosol.Mapping[uint256.Int, bool]
 
-	// NOTE: we don't care about this for now.
-	// IsAuthorized gosol.Mapping[common.Address, bool]
+	IsAuthorized gosol.Mapping[common.Address, gosol.Mapping[common.Address, bool]]
 
 	Nonce gosol.Mapping[common.Address, uint256.Int]
 
 	IdToMarketParams gosol.Mapping[common.Hash, MarketParams]
+
+	// Address is the address of the simulated contract, used for the EIP-712 domain separator
+	Address common.Address
+	// ChainId is the simulated block.chainid, used for the EIP-712 domain separator
+	ChainId uint64
+	// BlockTimestamp is the simulated block.timestamp
+	BlockTimestamp uint64
+
+	// Irms resolves IRM addresses to their implementation
+	Irms gosol.Mapping[common.Address, IRM]
+	// Oracles resolves oracle addresses to their implementation
+	Oracles gosol.Mapping[common.Address, Oracle]
+	// Callbacks resolves caller addresses to the contracts implementing the callback interfaces
+	Callbacks gosol.Mapping[common.Address, any]
+	// Balance is the ERC20 ledger of the simulation, token => account => balance.
+	// the balances of the contract itself are held by Address.
+	Balance gosol.Mapping[common.Address, gosol.Mapping[common.Address, uint256.Int]]
+
+	// Logs is the journal of the events emitted by the simulated operations, in emission order
+	Logs []Event
+
+	// Journal records the changes to the storage, logs and ledger of the contract, so that failed operations
+	// are reverted like in solidity. stateful IRMs can share it by wrapping their storage with gosol.NewJournaledMapping.
+	Journal *gosol.Journal
+
+	// factory creates the mappings of the contract, including the nested ones created on first write
+	factory gosol.MappingFactory
+}
+
+// Option configures a Morpho instance created by NewMorpho
+type Option func(*Morpho)
+
+// WithMappingFactory makes the Morpho instance back its mappings with the implementation described by factory.
+// concurrency-safe mappings allow reading the state while operations are applied, but operations themselves
+// must not run concurrently on the same instance, use Fork instead.
+func WithMappingFactory(factory gosol.MappingFactory) Option {
+	return func(m *Morpho) {
+		m.factory = factory
+	}
+}
+
+// WithBlockTimestamp sets the simulated block.timestamp of the Morpho instance, which is the current time by default
+func WithBlockTimestamp(timestamp uint64) Option {
+	return func(m *Morpho) {
+		m.BlockTimestamp = timestamp
+	}
 }
 
 // NewMorpho creates a new Morpho instance with the given owner and fee recipient
-func NewMorpho(owner, feeRecipient common.Address) *Morpho {
-	return &Morpho{
-		Owner:            owner,
-		FeeRecipient:     feeRecipient,
-		Position:         gosol.NewMapMapping[common.Hash, gosol.Mapping[common.Address, Position]](),
-		Market:           gosol.NewMapMapping[common.Hash, Market](),
-		IsIrmEnabled:     gosol.NewMapMapping[common.Address, bool](),
-		IsLltvEnabled:    gosol.NewMapMapping[common.Address, bool](),
-		Nonce:            gosol.NewMapMapping[common.Address, uint256.Int](),
-		IdToMarketParams: gosol.NewMapMapping[common.Hash, MarketParams](),
+func NewMorpho(owner, feeRecipient common.Address, options ...Option) *Morpho {
+	m := &Morpho{
+		Owner:          owner,
+		FeeRecipient:   feeRecipient,
+		BlockTimestamp: uint64(time.Now().Unix()),
+		Journal:        gosol.NewJournal(),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	f := m.factory
+	m.Position = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Hash, gosol.Mapping[common.Address, Position]](f))
+	m.Market = gosol.NewJournaledMapping(m.Journal, gosol.MakeEnumerableMapping[common.Hash, Market](f))
+	m.IsIrmEnabled = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Address, bool](f))
+	m.IsLltvEnabled = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[uint256.Int, bool](f))
+	m.IsAuthorized = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Address, gosol.Mapping[common.Address, bool]](f))
+	m.Nonce = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Address, uint256.Int](f))
+	m.IdToMarketParams = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Hash, MarketParams](f))
+	m.Irms = gosol.MakeMapping[common.Address, IRM](f)
+	m.Oracles = gosol.MakeMapping[common.Address, Oracle](f)
+	m.Callbacks = gosol.MakeMapping[common.Address, any](f)
+	m.Balance = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Address, gosol.Mapping[common.Address, uint256.Int]](f))
+	return m
+}
+
+// Snapshot returns an identifier for the current state of the contract
+func (m *Morpho) Snapshot() int {
+	return m.Journal.Snapshot()
+}
+
+// RevertToSnapshot reverts all changes made to the contract since the snapshot with the given id was taken
+func (m *Morpho) RevertToSnapshot(id int) error {
+	return m.Journal.RevertToSnapshot(id)
+}
+
+// GetMarketId returns the id of the market with the given params
+func (m *Morpho) GetMarketId(marketParams MarketParams) common.Hash {
+	return ComputeMarketId(marketParams)
+}
+
+// MarketIds returns the ids of the created markets, in creation order.
+// it fails with gosol.ErrNotEnumerable if the markets are not held in an enumerable mapping.
+func (m *Morpho) MarketIds() ([]common.Hash, error) {
+	markets, err := gosol.Enumerate(m.Market)
+	if err != nil {
+		return nil, err
+	}
+	return markets.Keys(), nil
+}
+
+// PositionUsers returns the users with a position in the given market, in the order of their first interaction.
+// it fails with gosol.ErrNotEnumerable if the positions are not held in an enumerable mapping.
+func (m *Morpho) PositionUsers(id common.Hash) ([]common.Address, error) {
+	positions, err := m.Position.Get(id)
+	if err != nil || positions == nil {
+		return nil, err
+	}
+	enumerable, err := gosol.Enumerate(positions)
+	if err != nil {
+		return nil, err
+	}
+	return enumerable.Keys(), nil
+}
+
+// SetOwner sets newOwner as the owner of the contract
+func (m *Morpho) SetOwner(caller, newOwner common.Address) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if caller != m.Owner {
+		return ErrorNotOwner
+	}
+	if newOwner == m.Owner {
+		return ErrorAlreadySet
+	}
+	prevOwner := m.Owner
+	m.Journal.Append(func() error {
+		m.Owner = prevOwner
+		return nil
+	})
+	m.Owner = newOwner
+	m.emit(SetOwnerEvent{NewOwner: newOwner})
+	return nil
+}
+
+// EnableIrm enables irm as a possible IRM for market creation
+func (m *Morpho) EnableIrm(caller, irm common.Address) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if caller != m.Owner {
+		return ErrorNotOwner
+	}
+	enabled, err := m.IsIrmEnabled.Get(irm)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		return ErrorAlreadySet
+	}
+	if err := m.IsIrmEnabled.Set(irm, true); err != nil {
+		return err
+	}
+	m.emit(EnableIrmEvent{Irm: irm})
+	return nil
+}
+
+// EnableLltv enables lltv as a possible LLTV for market creation
+func (m *Morpho) EnableLltv(caller common.Address, lltv *uint256.Int) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if caller != m.Owner {
+		return ErrorNotOwner
+	}
+	enabled, err := m.IsLltvEnabled.Get(*lltv)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		return ErrorAlreadySet
+	}
+	if !lltv.Lt(WAD) {
+		return ErrorMaxLltvExceeded
+	}
+	if err := m.IsLltvEnabled.Set(*lltv, true); err != nil {
+		return err
+	}
+	m.emit(EnableLltvEvent{Lltv: *lltv})
+	return nil
+}
+
+// SetFee sets the fee of the given market to newFee
+func (m *Morpho) SetFee(caller common.Address, marketParams MarketParams, newFee *uint256.Int) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if caller != m.Owner {
+		return ErrorNotOwner
+	}
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return err
+	}
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	if newFee.Eq(&market.Fee) {
+		return ErrorAlreadySet
+	}
+	if newFee.Gt(MAX_FEE) {
+		return ErrorMaxFeeExceeded
+	}
+
+	// accrue interest using the previous fee set before changing it
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return err
+	}
+
+	market, err = m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	market.Fee.Set(newFee)
+	if err := m.Market.Set(id, market); err != nil {
+		return err
+	}
+	m.emit(SetFeeEvent{Id: id, NewFee: *newFee})
+	return nil
+}
+
+// SetFeeRecipient sets newFeeRecipient as the recipient of the fee
+func (m *Morpho) SetFeeRecipient(caller, newFeeRecipient common.Address) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if caller != m.Owner {
+		return ErrorNotOwner
+	}
+	if newFeeRecipient == m.FeeRecipient {
+		return ErrorAlreadySet
+	}
+	prevFeeRecipient := m.FeeRecipient
+	m.Journal.Append(func() error {
+		m.FeeRecipient = prevFeeRecipient
+		return nil
+	})
+	m.FeeRecipient = newFeeRecipient
+	m.emit(SetFeeRecipientEvent{NewFeeRecipient: newFeeRecipient})
+	return nil
+}
+
+// CreateMarket creates the market with the given params at the current block timestamp
+func (m *Morpho) CreateMarket(caller common.Address, marketParams MarketParams) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	id := ComputeMarketId(marketParams)
+	irmEnabled, err := m.IsIrmEnabled.Get(marketParams.Irm)
+	if err != nil {
+		return err
+	}
+	if !irmEnabled {
+		return ErrorIrmNotEnabled
+	}
+	lltvEnabled, err := m.IsLltvEnabled.Get(marketParams.Lltv)
+	if err != nil {
+		return err
+	}
+	if !lltvEnabled {
+		return ErrorLltvNotEnabled
+	}
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	if !market.LastUpdate.IsZero() {
+		return ErrorMarketAlreadyCreated
+	}
+
+	market.LastUpdate.SetUint64(m.BlockTimestamp)
+	if err := m.Market.Set(id, market); err != nil {
+		return err
+	}
+	if err := m.IdToMarketParams.Set(id, marketParams); err != nil {
+		return err
+	}
+	m.emit(CreateMarketEvent{Id: id, MarketParams: marketParams})
+
+	// call the IRM to initialize it in case it is stateful, after the event like Morpho.sol
+	if marketParams.Irm != (common.Address{}) {
+		irm, err := m.getIrm(marketParams.Irm)
+		if err != nil {
+			return err
+		}
+		if _, err := irm.BorrowRate(marketParams, market); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Supply supplies assets or shares of the loan token on behalf of onBehalf.
+// msgValue must be zero since supply is not payable.
+func (m *Morpho) Supply(
+	caller common.Address,
+	msgValue *uint256.Int,
+	marketParams MarketParams,
+	assets, shares *uint256.Int,
+	onBehalf common.Address,
+	data []byte,
+) (_, _ *uint256.Int, err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if !msgValue.IsZero() {
+		return nil, nil, ErrorNonPayable
+	}
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return nil, nil, err
+	}
+	if !ExactlyOneZero(assets, shares) {
+		return nil, nil, ErrorInconsistentInput
+	}
+	if onBehalf == (common.Address{}) {
+		return nil, nil, ErrorZeroAddress
+	}
+
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return nil, nil, err
+	}
+
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	assets, shares = new(uint256.Int).Set(assets), new(uint256.Int).Set(shares)
+	if !assets.IsZero() {
+		shares, err = GetSharesFromAssets(assets, &market.TotalSupplyAssets, &market.TotalSupplyShares, false)
+	} else {
+		assets, err = GetAssetsFromShares(shares, &market.TotalSupplyAssets, &market.TotalSupplyShares, true)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	position, err := m.getPosition(id, onBehalf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := add(&position.SupplyShares, &position.SupplyShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := add128(&market.TotalSupplyShares, &market.TotalSupplyShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := add128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, assets); err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.setPosition(id, onBehalf, position); err != nil {
+		return nil, nil, err
+	}
+	if err := m.Market.Set(id, market); err != nil {
+		return nil, nil, err
+	}
+	m.emit(SupplyEvent{Id: id, Caller: caller, OnBehalf: onBehalf, Assets: *assets, Shares: *shares})
+
+	if len(data) > 0 {
+		callback, err := getCallback[MorphoSupplyCallback](m, caller)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := callback.OnMorphoSupply(new(uint256.Int).Set(assets), data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := m.safeTransferFrom(marketParams.LoanToken, caller, assets); err != nil {
+		return nil, nil, err
+	}
+	return assets, shares, nil
+}
+
+// Withdraw withdraws assets or shares of the loan token on behalf of onBehalf to receiver.
+func (m *Morpho) Withdraw(
+	caller common.Address,
+	marketParams MarketParams,
+	assets, shares *uint256.Int,
+	onBehalf, receiver common.Address,
+) (_, _ *uint256.Int, err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return nil, nil, err
+	}
+	if !ExactlyOneZero(assets, shares) {
+		return nil, nil, ErrorInconsistentInput
+	}
+	if receiver == (common.Address{}) {
+		return nil, nil, ErrorZeroAddress
+	}
+	// no need to verify that onBehalf is not the zero address thanks to the authorization check
+	authorized, err := m.isSenderAuthorized(caller, onBehalf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !authorized {
+		return nil, nil, ErrorUnauthorized
+	}
+
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return nil, nil, err
+	}
+
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	assets, shares = new(uint256.Int).Set(assets), new(uint256.Int).Set(shares)
+	if !assets.IsZero() {
+		shares, err = GetSharesFromAssets(assets, &market.TotalSupplyAssets, &market.TotalSupplyShares, true)
+	} else {
+		assets, err = GetAssetsFromShares(shares, &market.TotalSupplyAssets, &market.TotalSupplyShares, false)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	position, err := m.getPosition(id, onBehalf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sub(&position.SupplyShares, &position.SupplyShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := sub128(&market.TotalSupplyShares, &market.TotalSupplyShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := sub128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, assets); err != nil {
+		return nil, nil, err
+	}
+
+	if market.TotalBorrowAssets.Gt(&market.TotalSupplyAssets) {
+		return nil, nil, ErrorInsufficientLiquidity
+	}
+
+	if err := m.setPosition(id, onBehalf, position); err != nil {
+		return nil, nil, err
+	}
+	if err := m.Market.Set(id, market); err != nil {
+		return nil, nil, err
+	}
+	m.emit(WithdrawEvent{Id: id, Caller: caller, OnBehalf: onBehalf, Receiver: receiver, Assets: *assets, Shares: *shares})
+
+	if err := m.safeTransfer(marketParams.LoanToken, receiver, assets); err != nil {
+		return nil, nil, err
+	}
+	return assets, shares, nil
+}
+
+// Borrow borrows assets or shares of the loan token on behalf of onBehalf to receiver.
+func (m *Morpho) Borrow(
+	caller common.Address,
+	marketParams MarketParams,
+	assets, shares *uint256.Int,
+	onBehalf, receiver common.Address,
+) (_, _ *uint256.Int, err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return nil, nil, err
+	}
+	if !ExactlyOneZero(assets, shares) {
+		return nil, nil, ErrorInconsistentInput
+	}
+	if receiver == (common.Address{}) {
+		return nil, nil, ErrorZeroAddress
+	}
+	// no need to verify that onBehalf is not the zero address thanks to the authorization check
+	authorized, err := m.isSenderAuthorized(caller, onBehalf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !authorized {
+		return nil, nil, ErrorUnauthorized
+	}
+
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return nil, nil, err
+	}
+
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	assets, shares = new(uint256.Int).Set(assets), new(uint256.Int).Set(shares)
+	if !assets.IsZero() {
+		shares, err = GetSharesFromAssets(assets, &market.TotalBorrowAssets, &market.TotalBorrowShares, true)
+	} else {
+		assets, err = GetAssetsFromShares(shares, &market.TotalBorrowAssets, &market.TotalBorrowShares, false)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	position, err := m.getPosition(id, onBehalf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := add128(&position.BorrowShares, &position.BorrowShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := add128(&market.TotalBorrowShares, &market.TotalBorrowShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := add128(&market.TotalBorrowAssets, &market.TotalBorrowAssets, assets); err != nil {
+		return nil, nil, err
+	}
+
+	healthy, err := m.isHealthy(marketParams, &market, &position)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !healthy {
+		return nil, nil, ErrorInsufficientCollateral
+	}
+	if market.TotalBorrowAssets.Gt(&market.TotalSupplyAssets) {
+		return nil, nil, ErrorInsufficientLiquidity
+	}
+
+	if err := m.setPosition(id, onBehalf, position); err != nil {
+		return nil, nil, err
+	}
+	if err := m.Market.Set(id, market); err != nil {
+		return nil, nil, err
+	}
+	m.emit(BorrowEvent{Id: id, Caller: caller, OnBehalf: onBehalf, Receiver: receiver, Assets: *assets, Shares: *shares})
+
+	if err := m.safeTransfer(marketParams.LoanToken, receiver, assets); err != nil {
+		return nil, nil, err
+	}
+	return assets, shares, nil
+}
+
+// Repay repays assets or shares of the loan token on behalf of onBehalf.
+// msgValue must be zero since repay is not payable.
+func (m *Morpho) Repay(
+	caller common.Address,
+	msgValue *uint256.Int,
+	marketParams MarketParams,
+	assets, shares *uint256.Int,
+	onBehalf common.Address,
+	data []byte,
+) (_, _ *uint256.Int, err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if !msgValue.IsZero() {
+		return nil, nil, ErrorNonPayable
+	}
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return nil, nil, err
+	}
+	if !ExactlyOneZero(assets, shares) {
+		return nil, nil, ErrorInconsistentInput
+	}
+	if onBehalf == (common.Address{}) {
+		return nil, nil, ErrorZeroAddress
+	}
+
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return nil, nil, err
+	}
+
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	assets, shares = new(uint256.Int).Set(assets), new(uint256.Int).Set(shares)
+	if !assets.IsZero() {
+		shares, err = GetSharesFromAssets(assets, &market.TotalBorrowAssets, &market.TotalBorrowShares, false)
+	} else {
+		assets, err = GetAssetsFromShares(shares, &market.TotalBorrowAssets, &market.TotalBorrowShares, true)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	position, err := m.getPosition(id, onBehalf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sub128(&position.BorrowShares, &position.BorrowShares, shares); err != nil {
+		return nil, nil, err
+	}
+	if err := sub128(&market.TotalBorrowShares, &market.TotalBorrowShares, shares); err != nil {
+		return nil, nil, err
+	}
+	// repaid assets may exceed the total borrow assets by 1 because of rounding
+	ZeroFloorSub(&market.TotalBorrowAssets, &market.TotalBorrowAssets, assets)
+
+	if err := m.setPosition(id, onBehalf, position); err != nil {
+		return nil, nil, err
+	}
+	if err := m.Market.Set(id, market); err != nil {
+		return nil, nil, err
+	}
+	m.emit(RepayEvent{Id: id, Caller: caller, OnBehalf: onBehalf, Assets: *assets, Shares: *shares})
+
+	if len(data) > 0 {
+		callback, err := getCallback[MorphoRepayCallback](m, caller)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := callback.OnMorphoRepay(new(uint256.Int).Set(assets), data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := m.safeTransferFrom(marketParams.LoanToken, caller, assets); err != nil {
+		return nil, nil, err
+	}
+	return assets, shares, nil
+}
+
+// SupplyCollateral supplies assets of the collateral token on behalf of onBehalf.
+// msgValue must be zero since supplyCollateral is not payable.
+func (m *Morpho) SupplyCollateral(
+	caller common.Address,
+	msgValue *uint256.Int,
+	marketParams MarketParams,
+	assets *uint256.Int,
+	onBehalf common.Address,
+	data []byte,
+) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if !msgValue.IsZero() {
+		return ErrorNonPayable
+	}
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return err
+	}
+	if assets.IsZero() {
+		return ErrorZeroAssets
+	}
+	if onBehalf == (common.Address{}) {
+		return ErrorZeroAddress
+	}
+
+	// interest is not accrued because it's not required
+
+	position, err := m.getPosition(id, onBehalf)
+	if err != nil {
+		return err
+	}
+	if err := add128(&position.Collateral, &position.Collateral, assets); err != nil {
+		return err
+	}
+	if err := m.setPosition(id, onBehalf, position); err != nil {
+		return err
+	}
+	m.emit(SupplyCollateralEvent{Id: id, Caller: caller, OnBehalf: onBehalf, Assets: *assets})
+
+	if len(data) > 0 {
+		callback, err := getCallback[MorphoSupplyCollateralCallback](m, caller)
+		if err != nil {
+			return err
+		}
+		if err := callback.OnMorphoSupplyCollateral(new(uint256.Int).Set(assets), data); err != nil {
+			return err
+		}
+	}
+
+	return m.safeTransferFrom(marketParams.CollateralToken, caller, assets)
+}
+
+// WithdrawCollateral withdraws assets of the collateral token on behalf of onBehalf to receiver.
+func (m *Morpho) WithdrawCollateral(
+	caller common.Address,
+	marketParams MarketParams,
+	assets *uint256.Int,
+	onBehalf, receiver common.Address,
+) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return err
+	}
+	if assets.IsZero() {
+		return ErrorZeroAssets
+	}
+	if receiver == (common.Address{}) {
+		return ErrorZeroAddress
+	}
+	// no need to verify that onBehalf is not the zero address thanks to the authorization check
+	authorized, err := m.isSenderAuthorized(caller, onBehalf)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		return ErrorUnauthorized
+	}
+
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return err
+	}
+
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	position, err := m.getPosition(id, onBehalf)
+	if err != nil {
+		return err
+	}
+	if err := sub128(&position.Collateral, &position.Collateral, assets); err != nil {
+		return err
+	}
+
+	healthy, err := m.isHealthy(marketParams, &market, &position)
+	if err != nil {
+		return err
+	}
+	if !healthy {
+		return ErrorInsufficientCollateral
+	}
+
+	if err := m.setPosition(id, onBehalf, position); err != nil {
+		return err
+	}
+	m.emit(WithdrawCollateralEvent{Id: id, Caller: caller, OnBehalf: onBehalf, Receiver: receiver, Assets: *assets})
+
+	return m.safeTransfer(marketParams.CollateralToken, receiver, assets)
+}
+
+// Liquidate liquidates the given repaidShares of debt or seizes the given seizedAssets of collateral
+// of borrower, realizing bad debt if the borrower has no collateral left. It returns the seized
+// collateral and the repaid loan assets.
+// msgValue must be zero since liquidate is not payable.
+func (m *Morpho) Liquidate(
+	caller common.Address,
+	msgValue *uint256.Int,
+	marketParams MarketParams,
+	borrower common.Address,
+	seizedAssets, repaidShares *uint256.Int,
+	data []byte,
+) (_, _ *uint256.Int, err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if !msgValue.IsZero() {
+		return nil, nil, ErrorNonPayable
+	}
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return nil, nil, err
+	}
+	if !ExactlyOneZero(seizedAssets, repaidShares) {
+		return nil, nil, ErrorInconsistentInput
+	}
+
+	if err := m.accrueInterest(marketParams, id); err != nil {
+		return nil, nil, err
+	}
+
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	position, err := m.getPosition(id, borrower)
+	if err != nil {
+		return nil, nil, err
+	}
+	oracle, err := m.getOracle(marketParams.Oracle)
+	if err != nil {
+		return nil, nil, err
+	}
+	collateralPrice, err := oracle.Price()
+	if err != nil {
+		return nil, nil, err
+	}
+	healthy, err := isHealthy(marketParams, &market, &position, collateralPrice)
+	if err != nil {
+		return nil, nil, err
+	}
+	if healthy {
+		return nil, nil, ErrorHealthyPosition
+	}
+
+	liquidationIncentiveFactor, err := LiquidationIncentiveFactor(&marketParams.Lltv)
+	if err != nil {
+		return nil, nil, err
+	}
+	seizedAssets, repaidShares = new(uint256.Int).Set(seizedAssets), new(uint256.Int).Set(repaidShares)
+	if !seizedAssets.IsZero() {
+		seizedAssetsQuoted, err := MulDivRoundingUp(new(uint256.Int), seizedAssets, collateralPrice, ORACLE_PRICE_SCALE)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := WadDivUp(seizedAssetsQuoted, seizedAssetsQuoted, liquidationIncentiveFactor); err != nil {
+			return nil, nil, err
+		}
+		repaidShares, err = GetSharesFromAssets(seizedAssetsQuoted, &market.TotalBorrowAssets, &market.TotalBorrowShares, true)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		repaidAssetsIncentivized, err := GetAssetsFromShares(repaidShares, &market.TotalBorrowAssets, &market.TotalBorrowShares, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := WadMulDown(repaidAssetsIncentivized, repaidAssetsIncentivized, liquidationIncentiveFactor); err != nil {
+			return nil, nil, err
+		}
+		seizedAssets, err = MulDiv(new(uint256.Int), repaidAssetsIncentivized, ORACLE_PRICE_SCALE, collateralPrice)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	repaidAssets, err := GetAssetsFromShares(repaidShares, &market.TotalBorrowAssets, &market.TotalBorrowShares, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := sub128(&position.BorrowShares, &position.BorrowShares, repaidShares); err != nil {
+		return nil, nil, err
+	}
+	if err := sub128(&market.TotalBorrowShares, &market.TotalBorrowShares, repaidShares); err != nil {
+		return nil, nil, err
+	}
+	ZeroFloorSub(&market.TotalBorrowAssets, &market.TotalBorrowAssets, repaidAssets)
+	if err := sub128(&position.Collateral, &position.Collateral, seizedAssets); err != nil {
+		return nil, nil, err
+	}
+
+	badDebtAssets, badDebtShares := new(uint256.Int), new(uint256.Int)
+	if position.Collateral.IsZero() {
+		badDebtShares.Set(&position.BorrowShares)
+		badDebtAssets, err = GetAssetsFromShares(badDebtShares, &market.TotalBorrowAssets, &market.TotalBorrowShares, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		Min(badDebtAssets, &market.TotalBorrowAssets, badDebtAssets)
+
+		if err := sub128(&market.TotalBorrowAssets, &market.TotalBorrowAssets, badDebtAssets); err != nil {
+			return nil, nil, err
+		}
+		if err := sub128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, badDebtAssets); err != nil {
+			return nil, nil, err
+		}
+		if err := sub128(&market.TotalBorrowShares, &market.TotalBorrowShares, badDebtShares); err != nil {
+			return nil, nil, err
+		}
+		position.BorrowShares.Clear()
+	}
+
+	if err := m.setPosition(id, borrower, position); err != nil {
+		return nil, nil, err
+	}
+	if err := m.Market.Set(id, market); err != nil {
+		return nil, nil, err
+	}
+	m.emit(LiquidateEvent{
+		Id:            id,
+		Caller:        caller,
+		Borrower:      borrower,
+		RepaidAssets:  *repaidAssets,
+		RepaidShares:  *repaidShares,
+		SeizedAssets:  *seizedAssets,
+		BadDebtAssets: *badDebtAssets,
+		BadDebtShares: *badDebtShares,
+	})
+
+	if err := m.safeTransfer(marketParams.CollateralToken, caller, seizedAssets); err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) > 0 {
+		callback, err := getCallback[MorphoLiquidateCallback](m, caller)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := callback.OnMorphoLiquidate(new(uint256.Int).Set(repaidAssets), data); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := m.safeTransferFrom(marketParams.LoanToken, caller, repaidAssets); err != nil {
+		return nil, nil, err
+	}
+	return seizedAssets, repaidAssets, nil
+}
+
+// LiquidationIncentiveFactor returns the liquidation incentive factor of a market with the given lltv:
+// min(MAX_LIQUIDATION_INCENTIVE_FACTOR, WAD / (WAD - LIQUIDATION_CURSOR * (WAD - lltv)))
+func LiquidationIncentiveFactor(lltv *uint256.Int) (*uint256.Int, error) {
+	denominator, err := WadMulDown(new(uint256.Int), LIQUIDATION_CURSOR, new(uint256.Int).Sub(WAD, lltv))
+	if err != nil {
+		return nil, err
+	}
+	z, err := WadDivDown(new(uint256.Int), WAD, denominator.Sub(WAD, denominator))
+	if err != nil {
+		return nil, err
+	}
+	return Min(z, MAX_LIQUIDATION_INCENTIVE_FACTOR, z), nil
+}
+
+// IsHealthy returns whether the position of borrower is healthy at the given collateral price.
+// collateralPrice is the price of the collateral token quoted in the loan token, scaled by ORACLE_PRICE_SCALE.
+// it fails if the state cannot be read or the math overflows, where Morpho.sol reverts.
+func (m *Morpho) IsHealthy(marketParams MarketParams, id common.Hash, borrower common.Address, collateralPrice *uint256.Int) (bool, error) {
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return false, err
+	}
+	position, err := m.getPosition(id, borrower)
+	if err != nil {
+		return false, err
+	}
+	return isHealthy(marketParams, &market, &position, collateralPrice)
+}
+
+// FlashLoan lends assets of token to caller for the duration of the MorphoFlashLoanCallback,
+// and pulls them back afterwards.
+// msgValue must be zero since flashLoan is not payable.
+func (m *Morpho) FlashLoan(caller common.Address, msgValue *uint256.Int, token common.Address, assets *uint256.Int, data []byte) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	if !msgValue.IsZero() {
+		return ErrorNonPayable
+	}
+	if assets.IsZero() {
+		return ErrorZeroAssets
+	}
+
+	m.emit(FlashLoanEvent{Caller: caller, Token: token, Assets: *assets})
+
+	if err := m.safeTransfer(token, caller, assets); err != nil {
+		return err
+	}
+
+	callback, err := getCallback[MorphoFlashLoanCallback](m, caller)
+	if err != nil {
+		return err
+	}
+	if err := callback.OnMorphoFlashLoan(new(uint256.Int).Set(assets), data); err != nil {
+		return err
+	}
+
+	return m.safeTransferFrom(token, caller, assets)
+}
+
+// SetAuthorization sets the authorization for authorized to manage the positions of caller
+func (m *Morpho) SetAuthorization(caller, authorized common.Address, newIsAuthorized bool) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	isAuthorized, err := m.getIsAuthorized(caller, authorized)
+	if err != nil {
+		return err
+	}
+	if newIsAuthorized == isAuthorized {
+		return ErrorAlreadySet
+	}
+	if err := m.setIsAuthorized(caller, authorized, newIsAuthorized); err != nil {
+		return err
+	}
+	m.emit(SetAuthorizationEvent{Caller: caller, Authorizer: caller, Authorized: authorized, NewIsAuthorized: newIsAuthorized})
+	return nil
+}
+
+// SetAuthorizationWithSig sets the authorization of authorization.Authorized to manage the positions of
+// authorization.Authorizer, checking the EIP-712 signature of the authorizer
+func (m *Morpho) SetAuthorizationWithSig(caller common.Address, authorization Authorization, signature Signature) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	// the authorization is not checked to be already set because the nonce increment is a desired side effect
+	if uint256.NewInt(m.BlockTimestamp).Gt(&authorization.Deadline) {
+		return ErrorSignatureExpired
+	}
+	nonce, err := m.Nonce.Get(authorization.Authorizer)
+	if err != nil {
+		return err
+	}
+	if !authorization.Nonce.Eq(&nonce) {
+		return ErrorInvalidNonce
+	}
+
+	digest := AuthorizationDigest(m.DomainSeparator(), authorization)
+	signatory := Ecrecover(digest, signature)
+	if signatory == (common.Address{}) || signatory != authorization.Authorizer {
+		return ErrorInvalidSignature
+	}
+
+	if err := m.Nonce.Set(authorization.Authorizer, *new(uint256.Int).AddUint64(&nonce, 1)); err != nil {
+		return err
+	}
+	m.emit(IncrementNonceEvent{Caller: caller, Authorizer: authorization.Authorizer, UsedNonce: nonce})
+
+	if err := m.setIsAuthorized(authorization.Authorizer, authorization.Authorized, authorization.IsAuthorized); err != nil {
+		return err
+	}
+	m.emit(SetAuthorizationEvent{
+		Caller:          caller,
+		Authorizer:      authorization.Authorizer,
+		Authorized:      authorization.Authorized,
+		NewIsAuthorized: authorization.IsAuthorized,
+	})
+	return nil
+}
+
+// DomainSeparator returns the EIP-712 domain separator of the simulated contract
+func (m *Morpho) DomainSeparator() common.Hash {
+	return DomainSeparator(m.ChainId, m.Address)
+}
+
+// AccrueInterest accrues interest for the given market
+func (m *Morpho) AccrueInterest(marketParams MarketParams) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	id := ComputeMarketId(marketParams)
+	if err := m.requireMarketCreated(id); err != nil {
+		return err
+	}
+	return m.accrueInterest(marketParams, id)
+}
+
+// accrueInterest accrues interest for the given market, minting the fee shares to the fee recipient.
+// the IRM registered at marketParams.Irm is only called if time has elapsed since the last update.
+func (m *Morpho) accrueInterest(marketParams MarketParams, id common.Hash) error {
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	now := uint256.NewInt(m.BlockTimestamp)
+	elapsed, underflow := new(uint256.Int).SubOverflow(now, &market.LastUpdate)
+	if underflow {
+		return ErrorUint256Underflow
+	}
+	if elapsed.IsZero() {
+		return nil
+	}
+
+	if marketParams.Irm != (common.Address{}) {
+		irm, err := m.getIrm(marketParams.Irm)
+		if err != nil {
+			return err
+		}
+		borrowRate, err := irm.BorrowRate(marketParams, market)
+		if err != nil {
+			return err
+		}
+		growth := WadTaylorCompounded(new(uint256.Int), borrowRate, elapsed)
+		interest, err := WadMulDown(new(uint256.Int), &market.TotalBorrowAssets, growth)
+		if err != nil {
+			return err
+		}
+		if err := add128(&market.TotalBorrowAssets, &market.TotalBorrowAssets, interest); err != nil {
+			return err
+		}
+		if err := add128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, interest); err != nil {
+			return err
+		}
+
+		feeShares := new(uint256.Int)
+		if !market.Fee.IsZero() {
+			feeAmount, err := WadMulDown(new(uint256.Int), interest, &market.Fee)
+			if err != nil {
+				return err
+			}
+			// the fee amount is subtracted from the total supply in this calculation to compensate for the fact
+			// that total supply is already increased by the full interest (including the fee amount)
+			feeShares, err = GetSharesFromAssets(
+				feeAmount,
+				new(uint256.Int).Sub(&market.TotalSupplyAssets, feeAmount),
+				&market.TotalSupplyShares,
+				false,
+			)
+			if err != nil {
+				return err
+			}
+			position, err := m.getPosition(id, m.FeeRecipient)
+			if err != nil {
+				return err
+			}
+			if err := add(&position.SupplyShares, &position.SupplyShares, feeShares); err != nil {
+				return err
+			}
+			if err := add128(&market.TotalSupplyShares, &market.TotalSupplyShares, feeShares); err != nil {
+				return err
+			}
+			if err := m.setPosition(id, m.FeeRecipient, position); err != nil {
+				return err
+			}
+		}
+
+		m.emit(AccrueInterestEvent{Id: id, PrevBorrowRate: *borrowRate, Interest: *interest, FeeShares: *feeShares})
+	}
+
+	market.LastUpdate.Set(now)
+	return m.Market.Set(id, market)
+}
+
+// isHealthy checks the health of a position against the given market state, using the oracle registered at marketParams.Oracle.
+// the oracle is only called if the position has debt.
+func (m *Morpho) isHealthy(marketParams MarketParams, market *Market, position *Position) (bool, error) {
+	if position.BorrowShares.IsZero() {
+		return true, nil
+	}
+	oracle, err := m.getOracle(marketParams.Oracle)
+	if err != nil {
+		return false, err
+	}
+	collateralPrice, err := oracle.Price()
+	if err != nil {
+		return false, err
+	}
+	return isHealthy(marketParams, market, position, collateralPrice)
+}
+
+// isHealthy checks the health of a position against the given market state.
+// it fails like Morpho.sol reverts if the math overflows
+func isHealthy(marketParams MarketParams, market *Market, position *Position, collateralPrice *uint256.Int) (bool, error) {
+	if position.BorrowShares.IsZero() {
+		return true, nil
+	}
+	borrowed, err := GetAssetsFromShares(&position.BorrowShares, &market.TotalBorrowAssets, &market.TotalBorrowShares, true)
+	if err != nil {
+		return false, err
+	}
+	maxBorrow, err := MulDiv(new(uint256.Int), &position.Collateral, collateralPrice, ORACLE_PRICE_SCALE)
+	if err != nil {
+		return false, err
+	}
+	maxBorrow, err = WadMulDown(maxBorrow, maxBorrow, &marketParams.Lltv)
+	if err != nil {
+		return false, err
+	}
+	return !maxBorrow.Lt(borrowed), nil
+}
+
+// commitOrRevert reverts the contract to the given snapshot if *err is set, and discards the snapshot otherwise,
+// making the calling operation atomic
+func (m *Morpho) commitOrRevert(snapshot int, err *error) {
+	if *err == nil {
+		m.Journal.DiscardSnapshot(snapshot)
+		return
+	}
+	if revertErr := m.RevertToSnapshot(snapshot); revertErr != nil {
+		*err = errors.Join(*err, revertErr)
+	}
+}
+
+func (m *Morpho) isSenderAuthorized(caller, onBehalf common.Address) (bool, error) {
+	if caller == onBehalf {
+		return true, nil
+	}
+	return m.getIsAuthorized(onBehalf, caller)
+}
+
+func (m *Morpho) getIsAuthorized(authorizer, authorized common.Address) (bool, error) {
+	authorizations, err := m.IsAuthorized.Get(authorizer)
+	if err != nil || authorizations == nil {
+		return false, err
+	}
+	return authorizations.Get(authorized)
+}
+
+func (m *Morpho) setIsAuthorized(authorizer, authorized common.Address, isAuthorized bool) error {
+	authorizations, err := m.IsAuthorized.Get(authorizer)
+	if err != nil {
+		return err
+	}
+	if authorizations == nil {
+		authorizations = gosol.NewJournaledMapping(m.Journal, gosol.MakeMapping[common.Address, bool](m.factory))
+		if err := m.IsAuthorized.Set(authorizer, authorizations); err != nil {
+			return err
+		}
+	}
+	return authorizations.Set(authorized, isAuthorized)
+}
+
+func (m *Morpho) getIrm(address common.Address) (IRM, error) {
+	irm, err := m.Irms.Get(address)
+	if err != nil {
+		return nil, err
+	}
+	if irm == nil {
+		return nil, ErrorCallToNonContract
+	}
+	return irm, nil
+}
+
+func (m *Morpho) getOracle(address common.Address) (Oracle, error) {
+	oracle, err := m.Oracles.Get(address)
+	if err != nil {
+		return nil, err
+	}
+	if oracle == nil {
+		return nil, ErrorCallToNonContract
+	}
+	return oracle, nil
+}
+
+func (m *Morpho) requireMarketCreated(id common.Hash) error {
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	if market.LastUpdate.IsZero() {
+		return ErrorMarketNotCreated
+	}
+	return nil
+}
+
+func (m *Morpho) getPosition(id common.Hash, user common.Address) (Position, error) {
+	positions, err := m.Position.Get(id)
+	if err != nil || positions == nil {
+		return Position{}, err
+	}
+	return positions.Get(user)
+}
+
+func (m *Morpho) setPosition(id common.Hash, user common.Address, position Position) error {
+	positions, err := m.Position.Get(id)
+	if err != nil {
+		return err
+	}
+	if positions == nil {
+		positions = gosol.NewJournaledMapping(m.Journal, gosol.MakeEnumerableMapping[common.Address, Position](m.factory))
+		if err := m.Position.Set(id, positions); err != nil {
+			return err
+		}
+	}
+	return positions.Set(user, position)
+}
+
+// add sets z = x + y, failing on overflow like checked solidity arithmetic
+func add(z, x, y *uint256.Int) error {
+	if _, overflow := z.AddOverflow(x, y); overflow {
+		return ErrorUint256Overflow
+	}
+	return nil
+}
+
+// sub sets z = x - y, failing on underflow like checked solidity arithmetic
+func sub(z, x, y *uint256.Int) error {
+	if _, underflow := z.SubOverflow(x, y); underflow {
+		return ErrorUint256Underflow
+	}
+	return nil
+}
+
+// toUint128 mirrors UtilsLib.toUint128, failing with ErrorMaxUint128Exceeded
+func toUint128(x *uint256.Int) (gosol.Uint128, error) {
+	z, err := gosol.ToUint128(x)
+	if err != nil {
+		return gosol.Uint128{}, ErrorMaxUint128Exceeded
+	}
+	return z, nil
+}
+
+// add128 sets z = x + y.toUint128() for a uint128 field x, failing on overflow of the field
+func add128(z, x, y *uint256.Int) error {
+	x128, err := toUint128(x)
+	if err != nil {
+		return err
+	}
+	y128, err := toUint128(y)
+	if err != nil {
+		return err
+	}
+	z128, err := x128.Add(y128)
+	if err != nil {
+		return ErrorUint256Overflow
+	}
+	z.Set(z128.Uint256())
+	return nil
+}
+
+// sub128 sets z = x - y.toUint128() for a uint128 field x, failing on underflow of the field
+func sub128(z, x, y *uint256.Int) error {
+	x128, err := toUint128(x)
+	if err != nil {
+		return err
+	}
+	y128, err := toUint128(y)
+	if err != nil {
+		return err
+	}
+	z128, err := x128.Sub(y128)
+	if err != nil {
+		return ErrorUint256Underflow
 	}
+	z.Set(z128.Uint256())
+	return nil
 }
@@ -17,6 +17,15 @@ func (m *mockIRM) BorrowRate(marketParams MarketParams, market Market) (*uint256
 	return m.rate, nil
 }
 
+// Mock oracle for testing
+type mockOracle struct {
+	price *uint256.Int
+}
+
+func (m *mockOracle) Price() (*uint256.Int, error) {
+	return m.price, nil
+}
+
 func TestMorphoBasicOperations(t *testing.T) {
 	// Create a new Morpho instance
 	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
@@ -31,6 +40,7 @@ func TestMorphoBasicOperations(t *testing.T) {
 	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
 	lltv := uint256.MustFromDecimal("800000000000000000") // 80%
 
+	morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)})
 	err := morpho.EnableIrm(owner, irmAddr)
 	require.NoError(t, err)
 
@@ -67,6 +77,8 @@ func TestMorphoSupplyWithdraw(t *testing.T) {
 	morpho := NewMorpho(owner, owner)
 	
 	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	borrowRate := uint256.MustFromDecimal("50000000000000000") // 5%
+	morpho.Irms.Set(irmAddr, &mockIRM{rate: borrowRate})
 	morpho.EnableIrm(owner, irmAddr)
 	
 	lltv := uint256.MustFromDecimal("800000000000000000")
@@ -85,9 +97,9 @@ func TestMorphoSupplyWithdraw(t *testing.T) {
 	// Test supply
 	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
 	supplyAmount := uint256.MustFromDecimal("1000000000000000000") // 1 token
-	borrowRate := uint256.MustFromDecimal("50000000000000000") // 5%
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, supplyAmount))
 	
-	assets, shares, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil, borrowRate)
+	assets, shares, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
 	require.NoError(t, err)
 	require.NotNil(t, assets)
 	require.NotNil(t, shares)
@@ -100,8 +112,7 @@ func TestMorphoSupplyWithdraw(t *testing.T) {
 	require.True(t, position.SupplyShares.Cmp(uint256.NewInt(0)) > 0)
 	
 	// Test withdraw
-	collateralPrice := WAD // 1:1 price
-	withdrawAssets, withdrawShares, err := morpho.Withdraw(supplier, marketParams, assets, uint256.NewInt(0), supplier, supplier, borrowRate, collateralPrice)
+	withdrawAssets, withdrawShares, err := morpho.Withdraw(supplier, marketParams, assets, uint256.NewInt(0), supplier, supplier)
 	require.NoError(t, err)
 	require.Equal(t, assets.String(), withdrawAssets.String())
 	require.Equal(t, shares.String(), withdrawShares.String())
@@ -109,4 +120,265 @@ func TestMorphoSupplyWithdraw(t *testing.T) {
 	// Verify position after withdraw
 	position, _ = morpho.getPosition(marketId, supplier)
 	require.True(t, position.SupplyShares.IsZero())
-}
\ No newline at end of file
+
+	// the withdrawn assets are transferred back to the supplier
+	balance, _ := morpho.BalanceOf(marketParams.LoanToken, supplier)
+	require.Equal(t, supplyAmount.String(), balance.String())
+	balance, _ = morpho.BalanceOf(marketParams.LoanToken, morpho.Address)
+	require.True(t, balance.IsZero())
+}
+
+func TestMorphoOwnerFunctions(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	other := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)}))
+	require.Equal(t, ErrorNotOwner, morpho.EnableIrm(other, irmAddr))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.Equal(t, ErrorAlreadySet, morpho.EnableIrm(owner, irmAddr))
+
+	require.Equal(t, ErrorMaxLltvExceeded, morpho.EnableLltv(owner, WAD))
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	require.Equal(t, ErrorAlreadySet, morpho.EnableLltv(owner, lltv))
+
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *uint256.MustFromDecimal("900000000000000000"),
+	}
+	require.Equal(t, ErrorLltvNotEnabled, morpho.CreateMarket(other, marketParams))
+	marketParams.Lltv = *lltv
+	require.NoError(t, morpho.CreateMarket(other, marketParams))
+	require.Equal(t, ErrorMarketAlreadyCreated, morpho.CreateMarket(other, marketParams))
+
+	storedParams, _ := morpho.IdToMarketParams.Get(morpho.GetMarketId(marketParams))
+	require.True(t, MarketParamsEqual(marketParams, storedParams))
+
+	require.Equal(t, ErrorMaxFeeExceeded, morpho.SetFee(owner, marketParams, uint256.MustFromDecimal("250000000000000001")))
+	require.Equal(t, ErrorAlreadySet, morpho.SetFee(owner, marketParams, uint256.NewInt(0)))
+	require.NoError(t, morpho.SetFee(owner, marketParams, MAX_FEE))
+
+	require.Equal(t, ErrorAlreadySet, morpho.SetFeeRecipient(owner, owner))
+	require.NoError(t, morpho.SetFeeRecipient(owner, other))
+	require.Equal(t, other, morpho.FeeRecipient)
+
+	require.NoError(t, morpho.SetOwner(owner, other))
+	require.Equal(t, ErrorNotOwner, morpho.SetOwner(owner, owner))
+	require.Equal(t, other, morpho.Owner)
+}
+
+func TestMorphoAccrueInterest(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	morpho := NewMorpho(owner, feeRecipient)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	// creating a market initializes its IRM, which must be registered
+	require.Equal(t, ErrorCallToNonContract, morpho.CreateMarket(owner, marketParams))
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(1000000000)}))
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	marketId := morpho.GetMarketId(marketParams)
+
+	morpho.Market.Set(marketId, Market{
+		TotalSupplyAssets: *uint256.MustFromDecimal("1000000000000000000000"),
+		TotalSupplyShares: *uint256.MustFromDecimal("1000000000000000000000000000"),
+		TotalBorrowAssets: *uint256.MustFromDecimal("500000000000000000000"),
+		TotalBorrowShares: *uint256.MustFromDecimal("500000000000000000000000000"),
+		LastUpdate:        *uint256.NewInt(morpho.BlockTimestamp),
+		Fee:               *uint256.MustFromDecimal("100000000000000000"), // 10%
+	})
+
+	morpho.BlockTimestamp += 31536000
+	require.NoError(t, morpho.AccrueInterest(marketParams))
+
+	market, _ := morpho.Market.Get(marketId)
+	require.Equal(t, "1016019243420709888000", market.TotalSupplyAssets.String())
+	require.Equal(t, "516019243420709888000", market.TotalBorrowAssets.String())
+	require.Equal(t, "1001579157129854567892727813", market.TotalSupplyShares.String())
+	require.Equal(t, morpho.BlockTimestamp, market.LastUpdate.Uint64())
+
+	position, _ := morpho.getPosition(marketId, feeRecipient)
+	require.Equal(t, "1579157129854567892727813", position.SupplyShares.String())
+}
+
+func TestMorphoRevert(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(1000000000)}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	marketId := morpho.GetMarketId(marketParams)
+	created, _ := morpho.Market.Get(marketId)
+	morpho.Logs = nil
+
+	// a failed operation reverts the accrued interest, the position and the logs
+	morpho.BlockTimestamp += 3600
+	supplyAmount := uint256.MustFromDecimal("1000000000000000000")
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
+	require.Equal(t, ErrorTransferFromReverted, err)
+	market, _ := morpho.Market.Get(marketId)
+	require.Equal(t, created, market)
+	position, _ := morpho.getPosition(marketId, supplier)
+	require.True(t, position.SupplyShares.IsZero())
+	require.Empty(t, morpho.Logs)
+
+	// a bundle of operations reverts as a unit
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, supplyAmount))
+	snapshot := morpho.Snapshot()
+	_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+	require.NoError(t, morpho.SetFeeRecipient(owner, supplier))
+	require.Len(t, morpho.Logs, 3)
+
+	require.NoError(t, morpho.RevertToSnapshot(snapshot))
+	market, _ = morpho.Market.Get(marketId)
+	require.Equal(t, created, market)
+	position, _ = morpho.getPosition(marketId, supplier)
+	require.True(t, position.SupplyShares.IsZero())
+	balance, _ := morpho.BalanceOf(marketParams.LoanToken, supplier)
+	require.Equal(t, supplyAmount.String(), balance.String())
+	require.Equal(t, owner, morpho.FeeRecipient)
+	require.Empty(t, morpho.Logs)
+}
+
+func TestMorphoEnumerate(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	morpho := NewMorpho(owner, feeRecipient)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(1000000000)}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	var ids []common.Hash
+	var markets []MarketParams
+	for _, lltv := range []string{"800000000000000000", "860000000000000000"} {
+		require.NoError(t, morpho.EnableLltv(owner, uint256.MustFromDecimal(lltv)))
+		marketParams := MarketParams{
+			LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+			CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+			Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+			Irm:             irmAddr,
+			Lltv:            *uint256.MustFromDecimal(lltv),
+		}
+		require.NoError(t, morpho.CreateMarket(owner, marketParams))
+		require.NoError(t, morpho.SetFee(owner, marketParams, MAX_FEE))
+		ids = append(ids, ComputeMarketId(marketParams))
+		markets = append(markets, marketParams)
+	}
+	marketIds, err := morpho.MarketIds()
+	require.NoError(t, err)
+	require.Equal(t, ids, marketIds)
+
+	suppliers := []common.Address{
+		common.HexToAddress("0x5555555555555555555555555555555555555555"),
+		common.HexToAddress("0x6666666666666666666666666666666666666666"),
+	}
+	for _, supplier := range suppliers {
+		require.NoError(t, morpho.Deal(markets[0].LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000")))
+		_, _, err := morpho.Supply(supplier, uint256.NewInt(0), markets[0], uint256.MustFromDecimal("1000000000000000000"), uint256.NewInt(0), supplier, nil)
+		require.NoError(t, err)
+	}
+	// a reverted supply does not leave a position behind
+	_, _, err = morpho.Supply(owner, uint256.NewInt(0), markets[0], uint256.MustFromDecimal("1000000000000000000"), uint256.NewInt(0), owner, nil)
+	require.Equal(t, ErrorTransferFromReverted, err)
+	users, err := morpho.PositionUsers(ids[0])
+	require.NoError(t, err)
+	require.Equal(t, suppliers, users)
+	users, err = morpho.PositionUsers(ids[1])
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	// the supply shares of the positions add up to the total supply shares of each market, including in forks
+	morpho.BlockTimestamp += 31536000
+	require.NoError(t, morpho.AccrueInterest(markets[0]))
+	fork := morpho.Fork()
+	require.NoError(t, fork.Deal(markets[1].LoanToken, owner, uint256.MustFromDecimal("1000000000000000000")))
+	_, _, err = fork.Supply(owner, uint256.NewInt(0), markets[1], uint256.MustFromDecimal("1000000000000000000"), uint256.NewInt(0), owner, nil)
+	require.NoError(t, err)
+	for _, m := range []*Morpho{morpho, fork} {
+		ids, err := m.MarketIds()
+		require.NoError(t, err)
+		require.Len(t, ids, 2)
+		for _, id := range ids {
+			market, _ := m.Market.Get(id)
+			users, err := m.PositionUsers(id)
+			require.NoError(t, err)
+			total := new(uint256.Int)
+			for _, user := range users {
+				position, _ := m.getPosition(id, user)
+				total.Add(total, &position.SupplyShares)
+			}
+			require.Equal(t, market.TotalSupplyShares, *total)
+		}
+	}
+	users, err = fork.PositionUsers(ids[0])
+	require.NoError(t, err)
+	require.Equal(t, append(suppliers, feeRecipient), users)
+	users, err = morpho.PositionUsers(ids[1])
+	require.NoError(t, err)
+	require.Empty(t, users)
+}
+
+func TestMorphoUint128(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	morpho := NewMorpho(owner, owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, &MaxUint256))
+	require.NoError(t, morpho.Deal(marketParams.CollateralToken, supplier, &MaxUint256))
+
+	// the shares minted for max uint128 assets do not fit in the market totals
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, &MaxUint128, uint256.NewInt(0), supplier, nil)
+	require.Equal(t, ErrorMaxUint128Exceeded, err)
+
+	// the share math itself overflows
+	_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, &MaxUint256, uint256.NewInt(0), supplier, nil)
+	require.Equal(t, ErrorUint256Overflow, err)
+
+	require.NoError(t, morpho.SupplyCollateral(supplier, uint256.NewInt(0), marketParams, &MaxUint128, supplier, nil))
+	err = morpho.SupplyCollateral(supplier, uint256.NewInt(0), marketParams, uint256.NewInt(1), supplier, nil)
+	require.Equal(t, ErrorUint256Overflow, err)
+	err = morpho.SupplyCollateral(supplier, uint256.NewInt(0), marketParams, new(uint256.Int).AddUint64(&MaxUint128, 1), supplier, nil)
+	require.Equal(t, ErrorMaxUint128Exceeded, err)
+}
@@ -0,0 +1,129 @@
+package morphoblue
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// Oracle is the interface of an oracle, based on IOracle.sol
+// https://github.com/morpho-org/morpho-blue/blob/main/src/interfaces/IOracle.sol
+type Oracle interface {
+	// Price returns the price of 1 asset of collateral token quoted in 1 asset of loan token, scaled by ORACLE_PRICE_SCALE
+	Price() (*uint256.Int, error)
+}
+
+// ChainlinkFeed is the state of a chainlink aggregator used by MorphoChainlinkOracleV2
+type ChainlinkFeed struct {
+	// Answer is the int256 answer returned by latestRoundData
+	Answer   uint256.Int
+	Decimals uint64
+}
+
+// MorphoChainlinkOracleV2 computes prices offline the same way as MorphoChainlinkOracleV2.sol.
+// a nil feed or vault stands for address(0).
+// reference implementation:
+// https://github.com/morpho-org/morpho-blue-oracles/blob/main/src/morpho-chainlink/MorphoChainlinkOracleV2.sol
+type MorphoChainlinkOracleV2 struct {
+	// BaseVaultAssets is baseVault.convertToAssets(BaseVaultConversionSample), nil if there is no base vault
+	BaseVaultAssets           *uint256.Int
+	BaseVaultConversionSample uint256.Int
+	BaseFeed1                 *ChainlinkFeed
+	BaseFeed2                 *ChainlinkFeed
+	BaseTokenDecimals         uint64
+
+	// QuoteVaultAssets is quoteVault.convertToAssets(QuoteVaultConversionSample), nil if there is no quote vault
+	QuoteVaultAssets           *uint256.Int
+	QuoteVaultConversionSample uint256.Int
+	QuoteFeed1                 *ChainlinkFeed
+	QuoteFeed2                 *ChainlinkFeed
+	QuoteTokenDecimals         uint64
+}
+
+// ScaleFactor returns the SCALE_FACTOR the oracle is deployed with, checking the constructor requirements:
+// 10 ** (36 + quoteTokenDecimals + quoteFeed1Decimals + quoteFeed2Decimals - baseTokenDecimals - baseFeed1Decimals - baseFeed2Decimals)
+// * quoteVaultConversionSample / baseVaultConversionSample
+func (o *MorphoChainlinkOracleV2) ScaleFactor() (*uint256.Int, error) {
+	// the requirements are checked in the order of the constructor
+	if o.BaseVaultAssets == nil && !o.BaseVaultConversionSample.Eq(One) {
+		return nil, ErrorVaultConversionSampleIsNotOne
+	}
+	if o.QuoteVaultAssets == nil && !o.QuoteVaultConversionSample.Eq(One) {
+		return nil, ErrorVaultConversionSampleIsNotOne
+	}
+	if o.BaseVaultConversionSample.IsZero() {
+		return nil, ErrorZeroVaultConversionSample
+	}
+	if o.QuoteVaultConversionSample.IsZero() {
+		return nil, ErrorZeroVaultConversionSample
+	}
+
+	exponent := 36 + o.QuoteTokenDecimals + feedDecimals(o.QuoteFeed1) + feedDecimals(o.QuoteFeed2)
+	baseDecimals := o.BaseTokenDecimals + feedDecimals(o.BaseFeed1) + feedDecimals(o.BaseFeed2)
+	if baseDecimals > exponent {
+		return nil, ErrorUint256Underflow
+	}
+	exponent -= baseDecimals
+
+	// 10 ** 77 is the largest power of ten fitting in a uint256
+	if exponent > 77 {
+		return nil, ErrorUint256Overflow
+	}
+	z := new(uint256.Int).Exp(uint256.NewInt(10), uint256.NewInt(exponent))
+	if _, overflow := z.MulOverflow(z, &o.QuoteVaultConversionSample); overflow {
+		return nil, ErrorUint256Overflow
+	}
+	return z.Div(z, &o.BaseVaultConversionSample), nil
+}
+
+// Price returns the price of 1 asset of base token quoted in 1 asset of quote token, scaled by ORACLE_PRICE_SCALE
+func (o *MorphoChainlinkOracleV2) Price() (*uint256.Int, error) {
+	scaleFactor, err := o.ScaleFactor()
+	if err != nil {
+		return nil, err
+	}
+	numerator, err := oraclePriceProduct(o.BaseVaultAssets, o.BaseFeed1, o.BaseFeed2)
+	if err != nil {
+		return nil, err
+	}
+	denominator, err := oraclePriceProduct(o.QuoteVaultAssets, o.QuoteFeed1, o.QuoteFeed2)
+	if err != nil {
+		return nil, err
+	}
+	return MulDiv(scaleFactor, scaleFactor, numerator, denominator)
+}
+
+// oraclePriceProduct returns vaultAssets * feed1Price * feed2Price, where a missing vault or feed counts as 1
+func oraclePriceProduct(vaultAssets *uint256.Int, feed1, feed2 *ChainlinkFeed) (*uint256.Int, error) {
+	z := new(uint256.Int).SetOne()
+	if vaultAssets != nil {
+		z.Set(vaultAssets)
+	}
+	for _, feed := range []*ChainlinkFeed{feed1, feed2} {
+		price, err := feedPrice(feed)
+		if err != nil {
+			return nil, err
+		}
+		if _, overflow := z.MulOverflow(z, price); overflow {
+			return nil, ErrorUint256Overflow
+		}
+	}
+	return z, nil
+}
+
+// feedPrice mirrors ChainlinkDataFeedLib.getPrice
+func feedPrice(feed *ChainlinkFeed) (*uint256.Int, error) {
+	if feed == nil {
+		return new(uint256.Int).SetOne(), nil
+	}
+	if feed.Answer.Sign() < 0 {
+		return nil, ErrorNegativeAnswer
+	}
+	return new(uint256.Int).Set(&feed.Answer), nil
+}
+
+// feedDecimals mirrors ChainlinkDataFeedLib.getDecimals
+func feedDecimals(feed *ChainlinkFeed) uint64 {
+	if feed == nil {
+		return 0
+	}
+	return feed.Decimals
+}
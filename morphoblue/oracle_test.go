@@ -0,0 +1,72 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMorphoChainlinkOracleV2(t *testing.T) {
+	t.Run("WBTC/USDC with two feeds", func(t *testing.T) {
+		oracle := &MorphoChainlinkOracleV2{
+			BaseVaultConversionSample:  *uint256.NewInt(1),
+			BaseFeed1:                  &ChainlinkFeed{Answer: *uint256.NewInt(6000000000000), Decimals: 8}, // BTC/USD 60000
+			BaseTokenDecimals:          8,
+			QuoteVaultConversionSample: *uint256.NewInt(1),
+			QuoteFeed1:                 &ChainlinkFeed{Answer: *uint256.NewInt(100000000), Decimals: 8}, // USDC/USD 1
+			QuoteTokenDecimals:         6,
+		}
+		scaleFactor, err := oracle.ScaleFactor()
+		require.NoError(t, err)
+		require.Equal(t, "10000000000000000000000000000000000", scaleFactor.String())
+
+		price, err := oracle.Price()
+		require.NoError(t, err)
+		require.Equal(t, "600000000000000000000000000000000000000", price.String())
+	})
+
+	t.Run("sDAI/USDC with a base vault", func(t *testing.T) {
+		oracle := &MorphoChainlinkOracleV2{
+			BaseVaultAssets:            uint256.MustFromDecimal("1050000000000000000"),
+			BaseVaultConversionSample:  *uint256.MustFromDecimal("1000000000000000000"),
+			BaseFeed1:                  &ChainlinkFeed{Answer: *uint256.NewInt(100000000), Decimals: 8}, // DAI/USD 1
+			BaseTokenDecimals:          18,
+			QuoteVaultConversionSample: *uint256.NewInt(1),
+			QuoteFeed1:                 &ChainlinkFeed{Answer: *uint256.NewInt(100000000), Decimals: 8}, // USDC/USD 1
+			QuoteTokenDecimals:         6,
+		}
+		price, err := oracle.Price()
+		require.NoError(t, err)
+		require.Equal(t, "1050000000000000000000000", price.String())
+	})
+
+	t.Run("Negative answer", func(t *testing.T) {
+		oracle := &MorphoChainlinkOracleV2{
+			BaseVaultConversionSample:  *uint256.NewInt(1),
+			BaseFeed1:                  &ChainlinkFeed{Answer: *new(uint256.Int).Neg(uint256.NewInt(1)), Decimals: 8},
+			QuoteVaultConversionSample: *uint256.NewInt(1),
+		}
+		_, err := oracle.Price()
+		require.Equal(t, ErrorNegativeAnswer, err)
+	})
+
+	t.Run("Invalid conversion samples", func(t *testing.T) {
+		oracle := &MorphoChainlinkOracleV2{
+			BaseVaultConversionSample:  *uint256.NewInt(2),
+			QuoteVaultConversionSample: *uint256.NewInt(1),
+		}
+		_, err := oracle.ScaleFactor()
+		require.Equal(t, ErrorVaultConversionSampleIsNotOne, err)
+
+		oracle.BaseVaultAssets = uint256.NewInt(1)
+		oracle.BaseVaultConversionSample.Clear()
+		_, err = oracle.ScaleFactor()
+		require.Equal(t, ErrorZeroVaultConversionSample, err)
+
+		// both samples are checked to be one before being checked to be non zero
+		oracle.QuoteVaultConversionSample.SetUint64(2)
+		_, err = oracle.ScaleFactor()
+		require.Equal(t, ErrorVaultConversionSampleIsNotOne, err)
+	})
+}
@@ -0,0 +1,73 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+)
+
+// StateOverride returns the eth_call state override making the Morpho contract deployed at m.Address match
+// the simulated owner and fee recipient, the given markets and their params, the positions of the given users
+// in these markets, and the nonces of the given users.
+// the storage slots are overridden with StateDiff, so the rest of the contract storage is kept.
+func (m *Morpho) StateOverride(ids []common.Hash, users []common.Address) (map[common.Address]gethclient.OverrideAccount, error) {
+	target, storage, err := NewStorageOverride()
+	if err != nil {
+		return nil, err
+	}
+	storage[OWNER_SLOT] = gosol.AddressKey(m.Owner)
+	storage[FEE_RECIPIENT_SLOT] = gosol.AddressKey(m.FeeRecipient)
+	for _, id := range ids {
+		market, err := m.Market.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := target.Market.Set(id, market); err != nil {
+			return nil, err
+		}
+		marketParams, err := m.IdToMarketParams.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := target.IdToMarketParams.Set(id, marketParams); err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			position, err := m.getPosition(id, user)
+			if err != nil {
+				return nil, err
+			}
+			if err := target.setPosition(id, user, position); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, user := range users {
+		nonce, err := m.Nonce.Get(user)
+		if err != nil {
+			return nil, err
+		}
+		if err := target.Nonce.Set(user, nonce); err != nil {
+			return nil, err
+		}
+	}
+	return NewStateOverride(m.Address, storage), nil
+}
+
+// NewStateOverride returns the eth_call state override setting the given storage slots of the contract at address
+func NewStateOverride(address common.Address, storage gosol.MapStorage) map[common.Address]gethclient.OverrideAccount {
+	return map[common.Address]gethclient.OverrideAccount{
+		address: {StateDiff: storage},
+	}
+}
+
+// NewStorageOverride returns a Morpho instance writing to an empty storage, which can be used to build
+// a state override from state that is not held by a Morpho instance
+func NewStorageOverride() (*Morpho, gosol.MapStorage, error) {
+	storage := gosol.MapStorage{}
+	target, err := NewMorphoWithStorage(storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	return target, storage, nil
+}
@@ -0,0 +1,72 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateOverride(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	morpho := NewMorpho(owner, feeRecipient)
+	morpho.Address = common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb")
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	marketId := ComputeMarketId(marketParams)
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)}))
+	require.NoError(t, morpho.Oracles.Set(marketParams.Oracle, &mockOracle{price: ORACLE_PRICE_SCALE}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+	require.NoError(t, morpho.Deal(marketParams.CollateralToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+	require.NoError(t, morpho.SupplyCollateral(borrower, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("100000000000000000000"), borrower, nil))
+	_, _, err = morpho.Borrow(borrower, marketParams, uint256.MustFromDecimal("50000000000000000000"), uint256.NewInt(0), borrower, borrower)
+	require.NoError(t, err)
+	require.NoError(t, morpho.Nonce.Set(borrower, *uint256.NewInt(3)))
+
+	users := []common.Address{supplier, borrower}
+	override, err := morpho.StateOverride([]common.Hash{marketId}, users)
+	require.NoError(t, err)
+	require.Len(t, override, 1)
+	require.Contains(t, override, morpho.Address)
+
+	// decoding the overridden slots gives back the simulated state
+	decoded, err := NewMorphoWithStorage(gosol.MapStorage(override[morpho.Address].StateDiff))
+	require.NoError(t, err)
+	require.Equal(t, owner, decoded.Owner)
+	require.Equal(t, feeRecipient, decoded.FeeRecipient)
+	market, _ := morpho.Market.Get(marketId)
+	decodedMarket, err := decoded.Market.Get(marketId)
+	require.NoError(t, err)
+	require.Equal(t, market, decodedMarket)
+	decodedParams, err := decoded.IdToMarketParams.Get(marketId)
+	require.NoError(t, err)
+	require.Equal(t, marketParams, decodedParams)
+	for _, user := range users {
+		position, _ := morpho.getPosition(marketId, user)
+		decodedPosition, err := decoded.getPosition(marketId, user)
+		require.NoError(t, err)
+		require.Equal(t, position, decodedPosition)
+		nonce, _ := morpho.Nonce.Get(user)
+		decodedNonce, err := decoded.Nonce.Get(user)
+		require.NoError(t, err)
+		require.Equal(t, nonce, decodedNonce)
+	}
+}
@@ -0,0 +1,263 @@
+package morphoblue
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// ApplyMarketEvent updates market the same way Morpho Blue did when it emitted event at timestamp.
+// position returns the position of a user in the market of the event, which is updated in place,
+// and the fee shares of an AccrueInterestEvent are minted to feeRecipient.
+// on error, market and the positions are left partially updated and should be discarded
+func ApplyMarketEvent(
+	market *Market,
+	position func(user common.Address) (*Position, error),
+	feeRecipient common.Address,
+	timestamp uint64,
+	event MarketEvent,
+) error {
+	switch e := event.(type) {
+	case CreateMarketEvent:
+		*market = Market{}
+	case SetFeeEvent:
+		market.Fee.Set(&e.NewFee)
+	case SupplyEvent:
+		p, err := position(e.OnBehalf)
+		if err != nil {
+			return err
+		}
+		if err := add(&p.SupplyShares, &p.SupplyShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := add128(&market.TotalSupplyShares, &market.TotalSupplyShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := add128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, &e.Assets); err != nil {
+			return err
+		}
+	case WithdrawEvent:
+		p, err := position(e.OnBehalf)
+		if err != nil {
+			return err
+		}
+		if err := sub(&p.SupplyShares, &p.SupplyShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := sub128(&market.TotalSupplyShares, &market.TotalSupplyShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := sub128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, &e.Assets); err != nil {
+			return err
+		}
+	case BorrowEvent:
+		p, err := position(e.OnBehalf)
+		if err != nil {
+			return err
+		}
+		if err := add128(&p.BorrowShares, &p.BorrowShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := add128(&market.TotalBorrowShares, &market.TotalBorrowShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := add128(&market.TotalBorrowAssets, &market.TotalBorrowAssets, &e.Assets); err != nil {
+			return err
+		}
+	case RepayEvent:
+		p, err := position(e.OnBehalf)
+		if err != nil {
+			return err
+		}
+		if err := sub128(&p.BorrowShares, &p.BorrowShares, &e.Shares); err != nil {
+			return err
+		}
+		if err := sub128(&market.TotalBorrowShares, &market.TotalBorrowShares, &e.Shares); err != nil {
+			return err
+		}
+		ZeroFloorSub(&market.TotalBorrowAssets, &market.TotalBorrowAssets, &e.Assets)
+	case SupplyCollateralEvent:
+		// interest is not accrued when supplying collateral, so the last update is unchanged
+		p, err := position(e.OnBehalf)
+		if err != nil {
+			return err
+		}
+		return add128(&p.Collateral, &p.Collateral, &e.Assets)
+	case WithdrawCollateralEvent:
+		p, err := position(e.OnBehalf)
+		if err != nil {
+			return err
+		}
+		if err := sub128(&p.Collateral, &p.Collateral, &e.Assets); err != nil {
+			return err
+		}
+	case LiquidateEvent:
+		p, err := position(e.Borrower)
+		if err != nil {
+			return err
+		}
+		if err := sub128(&p.BorrowShares, &p.BorrowShares, &e.RepaidShares); err != nil {
+			return err
+		}
+		if err := sub128(&market.TotalBorrowShares, &market.TotalBorrowShares, &e.RepaidShares); err != nil {
+			return err
+		}
+		ZeroFloorSub(&market.TotalBorrowAssets, &market.TotalBorrowAssets, &e.RepaidAssets)
+		if err := sub128(&p.Collateral, &p.Collateral, &e.SeizedAssets); err != nil {
+			return err
+		}
+		// the bad debt is realized, the remaining borrow shares of the borrower are the bad debt shares
+		if err := sub128(&market.TotalBorrowAssets, &market.TotalBorrowAssets, &e.BadDebtAssets); err != nil {
+			return err
+		}
+		if err := sub128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, &e.BadDebtAssets); err != nil {
+			return err
+		}
+		if err := sub128(&market.TotalBorrowShares, &market.TotalBorrowShares, &e.BadDebtShares); err != nil {
+			return err
+		}
+		if err := sub128(&p.BorrowShares, &p.BorrowShares, &e.BadDebtShares); err != nil {
+			return err
+		}
+	case AccrueInterestEvent:
+		if err := add128(&market.TotalBorrowAssets, &market.TotalBorrowAssets, &e.Interest); err != nil {
+			return err
+		}
+		if err := add128(&market.TotalSupplyAssets, &market.TotalSupplyAssets, &e.Interest); err != nil {
+			return err
+		}
+		if !e.FeeShares.IsZero() {
+			p, err := position(feeRecipient)
+			if err != nil {
+				return err
+			}
+			if err := add(&p.SupplyShares, &p.SupplyShares, &e.FeeShares); err != nil {
+				return err
+			}
+			if err := add128(&market.TotalSupplyShares, &market.TotalSupplyShares, &e.FeeShares); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrorUnknownEvent, event.EventName())
+	}
+	market.LastUpdate.SetUint64(timestamp)
+	return nil
+}
+
+// ApplyEvents updates the state the same way Morpho Blue did when it emitted events at m.BlockTimestamp,
+// either all events are applied or none. the events are not emitted again, and the IRMs and oracles are not called,
+// except for the AdaptiveCurveIrms whose rates at target are updated on market creations and interest accruals.
+// accruing interest on a market without IRM emits no event, so such an update of its last update is missed until its next event
+func (m *Morpho) ApplyEvents(events ...Event) (err error) {
+	defer m.commitOrRevert(m.Snapshot(), &err)
+	for _, event := range events {
+		if err := m.applyEvent(event); err != nil {
+			return fmt.Errorf("%s: %w", event.EventName(), err)
+		}
+	}
+	return nil
+}
+
+func (m *Morpho) applyEvent(event Event) error {
+	switch e := event.(type) {
+	case SetOwnerEvent:
+		prevOwner := m.Owner
+		m.Journal.Append(func() error {
+			m.Owner = prevOwner
+			return nil
+		})
+		m.Owner = e.NewOwner
+		return nil
+	case SetFeeRecipientEvent:
+		prevFeeRecipient := m.FeeRecipient
+		m.Journal.Append(func() error {
+			m.FeeRecipient = prevFeeRecipient
+			return nil
+		})
+		m.FeeRecipient = e.NewFeeRecipient
+		return nil
+	case EnableIrmEvent:
+		return m.IsIrmEnabled.Set(e.Irm, true)
+	case EnableLltvEvent:
+		return m.IsLltvEnabled.Set(e.Lltv, true)
+	case SetAuthorizationEvent:
+		return m.setIsAuthorized(e.Authorizer, e.Authorized, e.NewIsAuthorized)
+	case IncrementNonceEvent:
+		return m.Nonce.Set(e.Authorizer, *new(uint256.Int).AddUint64(&e.UsedNonce, 1))
+	case FlashLoanEvent:
+		return nil
+	case MarketEvent:
+		return m.applyMarketEvent(e)
+	}
+	return ErrorUnknownEvent
+}
+
+func (m *Morpho) applyMarketEvent(event MarketEvent) error {
+	id := event.MarketId()
+	market, err := m.Market.Get(id)
+	if err != nil {
+		return err
+	}
+	if create, ok := event.(CreateMarketEvent); ok {
+		if !market.LastUpdate.IsZero() {
+			return ErrorMarketAlreadyCreated
+		}
+		if err := m.IdToMarketParams.Set(id, create.MarketParams); err != nil {
+			return err
+		}
+	} else if market.LastUpdate.IsZero() {
+		return ErrorMarketNotCreated
+	}
+
+	positions := map[common.Address]*Position{}
+	var users []common.Address
+	position := func(user common.Address) (*Position, error) {
+		if p, ok := positions[user]; ok {
+			return p, nil
+		}
+		p, err := m.getPosition(id, user)
+		if err != nil {
+			return nil, err
+		}
+		positions[user] = &p
+		users = append(users, user)
+		return &p, nil
+	}
+	switch event.(type) {
+	case CreateMarketEvent, AccrueInterestEvent:
+		// Morpho Blue called the IRM with the market before the event
+		if err := m.updateRateAtTarget(id, market); err != nil {
+			return err
+		}
+	}
+	if err := ApplyMarketEvent(&market, position, m.FeeRecipient, m.BlockTimestamp, event); err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := m.setPosition(id, user, *positions[user]); err != nil {
+			return err
+		}
+	}
+	return m.Market.Set(id, market)
+}
+
+// updateRateAtTarget stores the rate at target of the market if its IRM is an AdaptiveCurveIrm, other IRMs are stateless
+func (m *Morpho) updateRateAtTarget(id common.Hash, market Market) error {
+	marketParams, err := m.IdToMarketParams.Get(id)
+	if err != nil {
+		return err
+	}
+	irm, err := m.Irms.Get(marketParams.Irm)
+	if err != nil {
+		return err
+	}
+	adaptive, ok := irm.(*AdaptiveCurveIrm)
+	if !ok {
+		return nil
+	}
+	_, err = adaptive.BorrowRate(marketParams, market)
+	return err
+}
@@ -0,0 +1,192 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// replayBlock is the events emitted at a block timestamp
+type replayBlock struct {
+	timestamp uint64
+	events    []Event
+}
+
+// simulateReplay runs operations over several blocks, including fee accrual and a liquidation realizing bad debt,
+// with the IRM returned by newIrm, and returns the simulated state with the events emitted at each block
+func simulateReplay(t *testing.T, newIrm func(morpho *Morpho) IRM) (*Morpho, MarketParams, []replayBlock) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	liquidator := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *lltv,
+	}
+
+	morpho := NewMorpho(owner, owner)
+	oracle := &mockOracle{price: ORACLE_PRICE_SCALE}
+	require.NoError(t, morpho.Oracles.Set(marketParams.Oracle, oracle))
+	require.NoError(t, morpho.Irms.Set(marketParams.Irm, newIrm(morpho)))
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+	require.NoError(t, morpho.Deal(marketParams.CollateralToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, liquidator, uint256.MustFromDecimal("1000000000000000000000")))
+
+	var blocks []replayBlock
+	block := func(run func()) {
+		emitted := len(morpho.Logs)
+		run()
+		blocks = append(blocks, replayBlock{timestamp: morpho.BlockTimestamp, events: morpho.Logs[emitted:]})
+		morpho.BlockTimestamp += 86400
+	}
+	block(func() {
+		require.NoError(t, morpho.EnableIrm(owner, marketParams.Irm))
+		require.NoError(t, morpho.EnableLltv(owner, lltv))
+		require.NoError(t, morpho.CreateMarket(owner, marketParams))
+		require.NoError(t, morpho.SetFeeRecipient(owner, feeRecipient))
+		require.NoError(t, morpho.SetFee(owner, marketParams, uint256.MustFromDecimal("100000000000000000")))
+		require.NoError(t, morpho.SetAuthorization(borrower, liquidator, true))
+	})
+	block(func() {
+		_, _, err := morpho.Supply(supplier, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+		require.NoError(t, err)
+		require.NoError(t, morpho.SupplyCollateral(borrower, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("100000000000000000000"), borrower, nil))
+		_, _, err = morpho.Borrow(borrower, marketParams, uint256.MustFromDecimal("80000000000000000000"), uint256.NewInt(0), borrower, borrower)
+		require.NoError(t, err)
+	})
+	block(func() {
+		_, _, err := morpho.Repay(borrower, uint256.NewInt(0), marketParams, uint256.MustFromDecimal("1000000000000000000"), uint256.NewInt(0), borrower, nil)
+		require.NoError(t, err)
+		_, _, err = morpho.Withdraw(supplier, marketParams, uint256.MustFromDecimal("100000000000000000000"), uint256.NewInt(0), supplier, supplier)
+		require.NoError(t, err)
+		require.NoError(t, morpho.WithdrawCollateral(borrower, marketParams, uint256.MustFromDecimal("1000000000000000000"), borrower, borrower))
+	})
+	block(func() {
+		oracle.price = uint256.MustFromDecimal("500000000000000000000000000000000000")
+		_, _, err := morpho.Liquidate(liquidator, uint256.NewInt(0), marketParams, borrower, uint256.MustFromDecimal("99000000000000000000"), uint256.NewInt(0), nil)
+		require.NoError(t, err)
+	})
+	return morpho, marketParams, blocks
+}
+
+// replayBlocks applies the events of blocks to morpho at their block timestamp
+func replayBlocks(t *testing.T, morpho *Morpho, blocks []replayBlock) {
+	for _, block := range blocks {
+		morpho.BlockTimestamp = block.timestamp
+		// round trip the events through their logs, like an indexer would
+		events := make([]Event, len(block.events))
+		for i, event := range block.events {
+			log, err := EncodeLog(common.Address{}, event)
+			require.NoError(t, err)
+			events[i], err = DecodeLog(log)
+			require.NoError(t, err)
+		}
+		require.NoError(t, morpho.ApplyEvents(events...))
+	}
+}
+
+func TestApplyEvents(t *testing.T) {
+	morpho, marketParams, blocks := simulateReplay(t, func(*Morpho) IRM {
+		return &mockIRM{rate: uint256.NewInt(1000000000)}
+	})
+	id := ComputeMarketId(marketParams)
+
+	var liquidation LiquidateEvent
+	for _, event := range blocks[len(blocks)-1].events {
+		if e, ok := event.(LiquidateEvent); ok {
+			liquidation = e
+		}
+	}
+	require.False(t, liquidation.BadDebtShares.IsZero())
+
+	// the owner is set by the constructor, whose SetOwner event is not simulated
+	replayed := NewMorpho(morpho.Owner, common.Address{})
+	replayBlocks(t, replayed, blocks)
+
+	require.Equal(t, morpho.Owner, replayed.Owner)
+	require.Equal(t, morpho.FeeRecipient, replayed.FeeRecipient)
+	market, err := morpho.Market.Get(id)
+	require.NoError(t, err)
+	replayedMarket, err := replayed.Market.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, market, replayedMarket)
+	replayedParams, err := replayed.IdToMarketParams.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, marketParams, replayedParams)
+
+	users, err := morpho.PositionUsers(id)
+	require.NoError(t, err)
+	replayedUsers, err := replayed.PositionUsers(id)
+	require.NoError(t, err)
+	require.ElementsMatch(t, users, replayedUsers)
+	for _, user := range users {
+		position, err := morpho.getPosition(id, user)
+		require.NoError(t, err)
+		replayedPosition, err := replayed.getPosition(id, user)
+		require.NoError(t, err)
+		require.Equal(t, position, replayedPosition, user.Hex())
+	}
+	isAuthorized, err := replayed.isSenderAuthorized(liquidation.Caller, common.HexToAddress("0x6666666666666666666666666666666666666666"))
+	require.NoError(t, err)
+	require.True(t, isAuthorized)
+
+	t.Run("Failed events are not applied", func(t *testing.T) {
+		snapshot := replayedMarket
+		err := replayed.ApplyEvents(
+			SupplyEvent{Id: id, OnBehalf: liquidation.Caller, Assets: *uint256.NewInt(1), Shares: *uint256.NewInt(1)},
+			WithdrawEvent{Id: id, OnBehalf: liquidation.Caller, Assets: *uint256.NewInt(1), Shares: *uint256.NewInt(2)},
+		)
+		require.ErrorIs(t, err, ErrorUint256Underflow)
+		replayedMarket, err := replayed.Market.Get(id)
+		require.NoError(t, err)
+		require.Equal(t, snapshot, replayedMarket)
+
+		require.ErrorIs(t, replayed.ApplyEvents(SupplyEvent{Id: common.Hash{1}}), ErrorMarketNotCreated)
+		require.ErrorIs(t, replayed.ApplyEvents(CreateMarketEvent{Id: id, MarketParams: marketParams}), ErrorMarketAlreadyCreated)
+	})
+}
+
+func TestApplyEventsAdaptiveCurveIrm(t *testing.T) {
+	morpho, marketParams, blocks := simulateReplay(t, func(morpho *Morpho) IRM {
+		return NewAdaptiveCurveIrm(morpho)
+	})
+	id := ComputeMarketId(marketParams)
+
+	replayed := NewMorpho(morpho.Owner, common.Address{})
+	replayedIrm := NewAdaptiveCurveIrm(replayed)
+	require.NoError(t, replayed.Irms.Set(marketParams.Irm, replayedIrm))
+	replayBlocks(t, replayed, blocks)
+
+	market, err := morpho.Market.Get(id)
+	require.NoError(t, err)
+	replayedMarket, err := replayed.Market.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, market, replayedMarket)
+
+	irm, err := morpho.Irms.Get(marketParams.Irm)
+	require.NoError(t, err)
+	rateAtTarget, err := irm.(*AdaptiveCurveIrm).RateAtTarget.Get(id)
+	require.NoError(t, err)
+	require.NotEqual(t, AdaptiveIRM.INITIAL_RATE_AT_TARGET.String(), rateAtTarget.String())
+	replayedRateAtTarget, err := replayedIrm.RateAtTarget.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, rateAtTarget.String(), replayedRateAtTarget.String())
+
+	// the rate at target is restored when the events fail
+	replayed.BlockTimestamp += 86400
+	err = replayed.ApplyEvents(
+		AccrueInterestEvent{Id: id},
+		WithdrawEvent{Id: id, Assets: *uint256.NewInt(1), Shares: *uint256.NewInt(1)},
+	)
+	require.ErrorIs(t, err, ErrorUint256Underflow)
+	replayedRateAtTarget, err = replayedIrm.RateAtTarget.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, rateAtTarget.String(), replayedRateAtTarget.String())
+}
@@ -0,0 +1,224 @@
+package morphoblue
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+)
+
+// UnknownRevertError is a revert matching none of the known errors of a contract
+type UnknownRevertError struct {
+	// Err is the decoded revert, a *gosol.RevertError or a *gosol.CustomError
+	Err error
+}
+
+func (e *UnknownRevertError) Error() string {
+	return fmt.Sprintf("unknown revert: %s", e.Err)
+}
+
+func (e *UnknownRevertError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnknownRevertError) RevertData() []byte {
+	data, _ := gosol.EncodeRevert(e.Err)
+	return data
+}
+
+type revertEntry struct {
+	err    error
+	revert error
+}
+
+// Reverts maps the revert data raised by a contract to the sentinel errors, and back
+type Reverts struct {
+	entries []revertEntry
+}
+
+// with returns a copy of r raising err as revert
+func (r *Reverts) with(err error, revert error) *Reverts {
+	entries := make([]revertEntry, len(r.entries), len(r.entries)+1)
+	copy(entries, r.entries)
+	return &Reverts{entries: append(entries, revertEntry{err: err, revert: revert})}
+}
+
+// extend returns a copy of r also knowing the errors of other, such as the errors bubbled up from Morpho Blue
+func (r *Reverts) extend(other *Reverts) *Reverts {
+	entries := make([]revertEntry, 0, len(r.entries)+len(other.entries))
+	entries = append(entries, r.entries...)
+	return &Reverts{entries: append(entries, other.entries...)}
+}
+
+// Decode returns the sentinel error matching the revert data.
+// Custom errors with arguments are returned as a *gosol.CustomError which matches the sentinel with errors.Is,
+// panics and empty reverts are returned as decoded by gosol.DecodeRevert,
+// and any other revert as an *UnknownRevertError
+func (r *Reverts) Decode(data []byte) (error, error) {
+	decoded, err := gosol.DecodeRevert(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range r.entries {
+		if !errors.Is(decoded, entry.revert) {
+			continue
+		}
+		if custom, ok := decoded.(*gosol.CustomError); ok && len(custom.Args) > 0 {
+			custom.Signature = entry.revert.(*gosol.CustomError).Signature
+			return custom, nil
+		}
+		return entry.err, nil
+	}
+	switch decoded.(type) {
+	case *gosol.RevertError, *gosol.CustomError:
+		return &UnknownRevertError{Err: decoded}, nil
+	}
+	return decoded, nil
+}
+
+// Encode returns the revert data raised by the contract for err
+func (r *Reverts) Encode(err error) ([]byte, error) {
+	var custom *gosol.CustomError
+	if errors.As(err, &custom) && len(custom.Args) > 0 {
+		return custom.RevertData(), nil
+	}
+	for _, entry := range r.entries {
+		if errors.Is(err, entry.err) {
+			return gosol.EncodeRevert(entry.revert)
+		}
+	}
+	return gosol.EncodeRevert(err)
+}
+
+func newReverts(errs ...error) *Reverts {
+	r := &Reverts{}
+	for _, err := range errs {
+		r = r.with(err, err)
+	}
+	return r
+}
+
+// MorphoBlueReverts holds the errors raised by Morpho Blue
+var MorphoBlueReverts = newReverts(
+	ErrorNotOwner,
+	ErrorMaxLltvExceeded,
+	ErrorMaxFeeExceeded,
+	ErrorAlreadySet,
+	ErrorIrmNotEnabled,
+	ErrorLltvNotEnabled,
+	ErrorMarketAlreadyCreated,
+	ErrorNoCode,
+	ErrorMarketNotCreated,
+	ErrorInconsistentInput,
+	ErrorZeroAssets,
+	ErrorZeroAddress,
+	ErrorUnauthorized,
+	ErrorInsufficientCollateral,
+	ErrorInsufficientLiquidity,
+	ErrorHealthyPosition,
+	ErrorInvalidSignature,
+	ErrorSignatureExpired,
+	ErrorInvalidNonce,
+	ErrorTransferReverted,
+	ErrorTransferReturnedFalse,
+	ErrorTransferFromReverted,
+	ErrorTransferFromReturnedFalse,
+	ErrorMaxUint128Exceeded,
+	ErrorNegativeAnswer,
+	ErrorVaultConversionSampleIsNotOne,
+	ErrorZeroVaultConversionSample,
+)
+
+var erc4626Reverts = newReverts(
+	ErrorERC20InsufficientBalance,
+	ErrorERC20InsufficientAllowance,
+	ErrorERC4626ExceededMaxDeposit,
+	ErrorERC4626ExceededMaxMint,
+	ErrorERC4626ExceededMaxWithdraw,
+	ErrorERC4626ExceededMaxRedeem,
+)
+
+// MetaMorphoReverts holds the errors raised by MetaMorpho, including the errors bubbled up from Morpho Blue
+var MetaMorphoReverts = newReverts(
+	ErrorNotCuratorRole,
+	ErrorNotAllocatorRole,
+	ErrorNotGuardianRole,
+	ErrorNotCuratorNorGuardianRole,
+	ErrorUnauthorizedMarket,
+	ErrorInconsistentAsset,
+	ErrorSupplyCapExceeded,
+	ErrorAlreadyPending,
+	ErrorPendingCap,
+	ErrorPendingRemoval,
+	ErrorNonZeroCap,
+	ErrorDuplicateMarket,
+	ErrorInvalidMarketRemovalNonZeroCap,
+	ErrorInvalidMarketRemovalNonZeroSupply,
+	ErrorInvalidMarketRemovalTimelockNotElapsed,
+	ErrorNoPendingValue,
+	ErrorNotEnoughLiquidity,
+	ErrorMarketNotEnabled,
+	ErrorAboveMaxTimelock,
+	ErrorBelowMinTimelock,
+	ErrorTimelockNotElapsed,
+	ErrorMaxQueueLengthExceeded,
+	ErrorZeroFeeRecipient,
+	ErrorInconsistentReallocation,
+	ErrorAllCapsReached,
+).
+	with(ErrorZeroAddress, customError("ZeroAddress()")).
+	with(ErrorMarketNotCreated, customError("MarketNotCreated()")).
+	with(ErrorAlreadySet, customError("AlreadySet()")).
+	with(ErrorMaxFeeExceeded, customError("MaxFeeExceeded()")).
+	extend(erc4626Reverts).
+	extend(MorphoBlueReverts)
+
+// VaultV2Reverts holds the errors raised by VaultV2, including the errors bubbled up from Morpho Blue through its adapters
+var VaultV2Reverts = newReverts(
+	ErrorAbsoluteCapExceeded,
+	ErrorAbsoluteCapNotDecreasing,
+	ErrorAbsoluteCapNotIncreasing,
+	ErrorRelativeCapAboveOne,
+	ErrorRelativeCapExceeded,
+	ErrorRelativeCapNotDecreasing,
+	ErrorRelativeCapNotIncreasing,
+	ErrorZeroAbsoluteCap,
+	ErrorZeroAllocation,
+	ErrorAutomaticallyTimelocked,
+	ErrorDataAlreadyPending,
+	ErrorDataNotTimelocked,
+	ErrorTimelockNotExpired,
+	ErrorTimelockNotDecreasing,
+	ErrorTimelockNotIncreasing,
+	ErrorCannotReceiveShares,
+	ErrorCannotReceiveAssets,
+	ErrorCannotSendShares,
+	ErrorCannotSendAssets,
+	ErrorCastOverflow,
+	ErrorFeeInvariantBroken,
+	ErrorFeeTooHigh,
+	ErrorInvalidSigner,
+	ErrorMaxRateTooHigh,
+	ErrorNotAdapter,
+	ErrorNotInAdapterRegistry,
+	ErrorPenaltyTooHigh,
+	ErrorPermitDeadlineExpired,
+).
+	with(ErrorUnauthorized, customError("Unauthorized()")).
+	with(ErrorZeroAddress, customError("ZeroAddress()")).
+	with(ErrorNoCode, customError("NoCode()")).
+	with(ErrorTransferReverted, customError("TransferReverted()")).
+	with(ErrorTransferReturnedFalse, customError("TransferReturnedFalse()")).
+	with(ErrorTransferFromReverted, customError("TransferFromReverted()")).
+	with(ErrorTransferFromReturnedFalse, customError("TransferFromReturnedFalse()")).
+	extend(MorphoBlueReverts)
+
+// DecodeRevert returns the Morpho Blue sentinel error matching the revert data
+func DecodeRevert(data []byte) (error, error) {
+	return MorphoBlueReverts.Decode(data)
+}
+
+// EncodeRevert returns the revert data raised by Morpho Blue for err
+func EncodeRevert(err error) ([]byte, error) {
+	return MorphoBlueReverts.Encode(err)
+}
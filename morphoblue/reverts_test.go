@@ -0,0 +1,95 @@
+package morphoblue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverts(t *testing.T) {
+	t.Run("Morpho Blue", func(t *testing.T) {
+		// Error("insufficient collateral")
+		data := hexutil.MustDecode("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000017" +
+			"696e73756666696369656e7420636f6c6c61746572616c000000000000000000")
+		err, decodeErr := DecodeRevert(data)
+		require.NoError(t, decodeErr)
+		require.Equal(t, ErrorInsufficientCollateral, err)
+
+		encoded, encodeErr := EncodeRevert(err)
+		require.NoError(t, encodeErr)
+		require.Equal(t, data, encoded)
+
+		// panics are kept as such, and match the math sentinels
+		err, decodeErr = DecodeRevert(gosol.Panic(gosol.PanicArithmetic).(gosol.Revert).RevertData())
+		require.NoError(t, decodeErr)
+		require.ErrorIs(t, err, ErrorUint256Overflow)
+
+		err, decodeErr = DecodeRevert(gosol.Require(false, "not a morpho error").(gosol.Revert).RevertData())
+		require.NoError(t, decodeErr)
+		var unknown *UnknownRevertError
+		require.True(t, errors.As(err, &unknown))
+		require.EqualError(t, err, "unknown revert: not a morpho error")
+		encoded, encodeErr = EncodeRevert(err)
+		require.NoError(t, encodeErr)
+		require.Equal(t, gosol.Require(false, "not a morpho error").(gosol.Revert).RevertData(), encoded)
+
+		_, decodeErr = DecodeRevert([]byte{0x01})
+		require.ErrorIs(t, decodeErr, gosol.ErrInvalidRevertData)
+	})
+
+	t.Run("MetaMorpho", func(t *testing.T) {
+		err, decodeErr := MetaMorphoReverts.Decode(hexutil.MustDecode("0xca899cec"))
+		require.NoError(t, decodeErr)
+		require.Equal(t, ErrorNotCuratorRole, err)
+
+		// MetaMorpho raises its own ZeroAddress()
+		encoded, encodeErr := MetaMorphoReverts.Encode(ErrorZeroAddress)
+		require.NoError(t, encodeErr)
+		require.Equal(t, "0xd92e233d", hexutil.Encode(encoded))
+
+		// the arguments of the error are kept
+		id := common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+		supplyCapExceeded, newErr := gosol.NewCustomError("SupplyCapExceeded(bytes32)", id)
+		require.NoError(t, newErr)
+		err, decodeErr = MetaMorphoReverts.Decode(supplyCapExceeded.RevertData())
+		require.NoError(t, decodeErr)
+		require.ErrorIs(t, err, ErrorSupplyCapExceeded)
+		require.NotErrorIs(t, err, ErrorPendingCap)
+		args, unpackErr := err.(*gosol.CustomError).Unpack()
+		require.NoError(t, unpackErr)
+		require.Equal(t, []any{[32]byte(id)}, args)
+		encoded, encodeErr = MetaMorphoReverts.Encode(err)
+		require.NoError(t, encodeErr)
+		require.Equal(t, supplyCapExceeded.RevertData(), encoded)
+
+		// errors bubbled up from Morpho Blue
+		err, decodeErr = MetaMorphoReverts.Decode(ErrorInsufficientLiquidity.(gosol.Revert).RevertData())
+		require.NoError(t, decodeErr)
+		require.Equal(t, ErrorInsufficientLiquidity, err)
+	})
+
+	t.Run("VaultV2", func(t *testing.T) {
+		err, decodeErr := VaultV2Reverts.Decode(hexutil.MustDecode("0x82b42900"))
+		require.NoError(t, decodeErr)
+		require.Equal(t, ErrorUnauthorized, err)
+
+		encoded, encodeErr := VaultV2Reverts.Encode(ErrorUnauthorized)
+		require.NoError(t, encodeErr)
+		require.Equal(t, "0x82b42900", hexutil.Encode(encoded))
+
+		// Morpho Blue still reverts with the reason string
+		encoded, encodeErr = EncodeRevert(ErrorUnauthorized)
+		require.NoError(t, encodeErr)
+		require.Equal(t, ErrorUnauthorized.(gosol.Revert).RevertData(), encoded)
+
+		err, decodeErr = VaultV2Reverts.Decode(hexutil.MustDecode("0xdeadbeef"))
+		require.NoError(t, decodeErr)
+		require.EqualError(t, err, "unknown revert: custom error 0xdeadbeef")
+	})
+}
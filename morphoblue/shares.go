@@ -9,32 +9,54 @@ var (
 	virtualAssets = uint256.NewInt(1)
 )
 
+// GetAssetsFromShares mirrors SharesMathLib.toAssetsDown and toAssetsUp, failing on overflow like checked solidity arithmetic
 func GetAssetsFromShares(shares, totalAssets, totalShares *uint256.Int, roundUp bool) (*uint256.Int, error) {
-	if roundUp {
-		return MulDivRoundingUp(new(uint256.Int),
-			shares,
-			new(uint256.Int).Add(totalAssets, virtualAssets),
-			new(uint256.Int).Add(totalShares, virtualShares),
-		)
-	}
-	return MulDiv(new(uint256.Int),
-		shares,
-		new(uint256.Int).Add(totalAssets, virtualAssets),
-		new(uint256.Int).Add(totalShares, virtualShares),
-	)
+	assets, err := withVirtual(totalAssets, virtualAssets)
+	if err != nil {
+		return nil, err
+	}
+	sharesWithVirtual, err := withVirtual(totalShares, virtualShares)
+	if err != nil {
+		return nil, err
+	}
+	return checkedMulDiv(shares, assets, sharesWithVirtual, roundUp)
 }
 
+// GetSharesFromAssets mirrors SharesMathLib.toSharesDown and toSharesUp, failing on overflow like checked solidity arithmetic
 func GetSharesFromAssets(assets, totalAssets, totalShares *uint256.Int, roundUp bool) (*uint256.Int, error) {
+	sharesWithVirtual, err := withVirtual(totalShares, virtualShares)
+	if err != nil {
+		return nil, err
+	}
+	assetsWithVirtual, err := withVirtual(totalAssets, virtualAssets)
+	if err != nil {
+		return nil, err
+	}
+	return checkedMulDiv(assets, sharesWithVirtual, assetsWithVirtual, roundUp)
+}
+
+func withVirtual(total, virtual *uint256.Int) (*uint256.Int, error) {
+	z := new(uint256.Int)
+	if err := add(z, total, virtual); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// checkedMulDiv mirrors MathLib.mulDivDown and mulDivUp, (x * y) / d and (x * y + (d - 1)) / d,
+// which overflow as soon as an intermediate result does
+func checkedMulDiv(x, y, d *uint256.Int, roundUp bool) (*uint256.Int, error) {
+	if d.IsZero() {
+		return nil, ErrorDivideByZero
+	}
+	z, overflow := new(uint256.Int).MulOverflow(x, y)
+	if overflow {
+		return nil, ErrorUint256Overflow
+	}
 	if roundUp {
-		return MulDivRoundingUp(new(uint256.Int),
-			assets,
-			new(uint256.Int).Add(totalShares, virtualShares),
-			new(uint256.Int).Add(totalAssets, virtualAssets),
-		)
-	}
-	return MulDiv(new(uint256.Int),
-		assets,
-		new(uint256.Int).Add(totalShares, virtualShares),
-		new(uint256.Int).Add(totalAssets, virtualAssets),
-	)
+		if err := add(z, z, new(uint256.Int).SubUint64(d, 1)); err != nil {
+			return nil, err
+		}
+	}
+	return z.Div(z, d), nil
 }
@@ -0,0 +1,164 @@
+package morphoblue
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+)
+
+// Morpho.sol storage layout, DOMAIN_SEPARATOR being immutable it does not use a slot
+var (
+	OWNER_SLOT               = gosol.Slot(0)
+	FEE_RECIPIENT_SLOT       = gosol.Slot(1)
+	POSITION_SLOT            = gosol.Slot(2)
+	MARKET_SLOT              = gosol.Slot(3)
+	IS_IRM_ENABLED_SLOT      = gosol.Slot(4)
+	IS_LLTV_ENABLED_SLOT     = gosol.Slot(5)
+	IS_AUTHORIZED_SLOT       = gosol.Slot(6)
+	NONCE_SLOT               = gosol.Slot(7)
+	ID_TO_MARKET_PARAMS_SLOT = gosol.Slot(8)
+)
+
+// PositionSlot returns the first storage slot of position[id][user]
+func PositionSlot(id common.Hash, user common.Address) common.Hash {
+	return gosol.MappingSlot(gosol.AddressKey(user), gosol.MappingSlot(gosol.HashKey(id), POSITION_SLOT))
+}
+
+// MarketSlot returns the first storage slot of market[id]
+func MarketSlot(id common.Hash) common.Hash {
+	return gosol.MappingSlot(gosol.HashKey(id), MARKET_SLOT)
+}
+
+// MarketParamsSlot returns the first storage slot of idToMarketParams[id]
+func MarketParamsSlot(id common.Hash) common.Hash {
+	return gosol.MappingSlot(gosol.HashKey(id), ID_TO_MARKET_PARAMS_SLOT)
+}
+
+// PositionCodec stores a Position in 2 words: supplyShares, then borrowShares packed with collateral
+type PositionCodec struct{}
+
+func (PositionCodec) Words() uint64 { return 2 }
+
+func (PositionCodec) Decode(words []common.Hash) (Position, error) {
+	if len(words) != 2 {
+		return Position{}, ErrorInconsistentInput
+	}
+	borrowShares, collateral := gosol.UnpackUint128(words[1])
+	return Position{
+		SupplyShares: *new(uint256.Int).SetBytes32(words[0].Bytes()),
+		BorrowShares: *borrowShares,
+		Collateral:   *collateral,
+	}, nil
+}
+
+func (PositionCodec) Encode(position Position) ([]common.Hash, error) {
+	word, err := packUint128(&position.BorrowShares, &position.Collateral)
+	if err != nil {
+		return nil, err
+	}
+	return []common.Hash{position.SupplyShares.Bytes32(), word}, nil
+}
+
+// MarketCodec stores a Market in 3 words of packed uint128 pairs
+type MarketCodec struct{}
+
+func (MarketCodec) Words() uint64 { return 3 }
+
+func (MarketCodec) Decode(words []common.Hash) (Market, error) {
+	if len(words) != 3 {
+		return Market{}, ErrorInconsistentInput
+	}
+	totalSupplyAssets, totalSupplyShares := gosol.UnpackUint128(words[0])
+	totalBorrowAssets, totalBorrowShares := gosol.UnpackUint128(words[1])
+	lastUpdate, fee := gosol.UnpackUint128(words[2])
+	return Market{
+		TotalSupplyAssets: *totalSupplyAssets,
+		TotalSupplyShares: *totalSupplyShares,
+		TotalBorrowAssets: *totalBorrowAssets,
+		TotalBorrowShares: *totalBorrowShares,
+		LastUpdate:        *lastUpdate,
+		Fee:               *fee,
+	}, nil
+}
+
+func (MarketCodec) Encode(market Market) ([]common.Hash, error) {
+	words := make([]common.Hash, 3)
+	pairs := [3][2]*uint256.Int{
+		{&market.TotalSupplyAssets, &market.TotalSupplyShares},
+		{&market.TotalBorrowAssets, &market.TotalBorrowShares},
+		{&market.LastUpdate, &market.Fee},
+	}
+	for i, pair := range pairs {
+		word, err := packUint128(pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		words[i] = word
+	}
+	return words, nil
+}
+
+// MarketParamsCodec stores MarketParams in 5 words, one per field
+type MarketParamsCodec struct{}
+
+func (MarketParamsCodec) Words() uint64 { return 5 }
+
+func (MarketParamsCodec) Decode(words []common.Hash) (MarketParams, error) {
+	if len(words) != 5 {
+		return MarketParams{}, ErrorInconsistentInput
+	}
+	return MarketParams{
+		LoanToken:       common.BytesToAddress(words[0].Bytes()),
+		CollateralToken: common.BytesToAddress(words[1].Bytes()),
+		Oracle:          common.BytesToAddress(words[2].Bytes()),
+		Irm:             common.BytesToAddress(words[3].Bytes()),
+		Lltv:            *new(uint256.Int).SetBytes32(words[4].Bytes()),
+	}, nil
+}
+
+func (MarketParamsCodec) Encode(marketParams MarketParams) ([]common.Hash, error) {
+	return []common.Hash{
+		gosol.AddressKey(marketParams.LoanToken),
+		gosol.AddressKey(marketParams.CollateralToken),
+		gosol.AddressKey(marketParams.Oracle),
+		gosol.AddressKey(marketParams.Irm),
+		marketParams.Lltv.Bytes32(),
+	}, nil
+}
+
+// NewMorphoWithStorage creates a Morpho instance whose mappings are read from and written to storage,
+// following the storage layout of Morpho.sol.
+// the owner and fee recipient are loaded from storage, but are not written back to it.
+func NewMorphoWithStorage(storage gosol.Storage, options ...Option) (*Morpho, error) {
+	owner, err := storage.GetState(OWNER_SLOT)
+	if err != nil {
+		return nil, err
+	}
+	feeRecipient, err := storage.GetState(FEE_RECIPIENT_SLOT)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMorpho(common.BytesToAddress(owner.Bytes()), common.BytesToAddress(feeRecipient.Bytes()), options...)
+	journal := m.Journal
+
+	m.Position = gosol.NewNestedStorageMapping(POSITION_SLOT, gosol.HashKey, func(slot common.Hash) gosol.Mapping[common.Address, Position] {
+		return gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[common.Address, Position](storage, slot, gosol.AddressKey, PositionCodec{}))
+	})
+	m.Market = gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[common.Hash, Market](storage, MARKET_SLOT, gosol.HashKey, MarketCodec{}))
+	m.IsIrmEnabled = gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[common.Address, bool](storage, IS_IRM_ENABLED_SLOT, gosol.AddressKey, gosol.BoolCodec{}))
+	m.IsLltvEnabled = gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[uint256.Int, bool](storage, IS_LLTV_ENABLED_SLOT, gosol.Uint256Key, gosol.BoolCodec{}))
+	m.IsAuthorized = gosol.NewNestedStorageMapping(IS_AUTHORIZED_SLOT, gosol.AddressKey, func(slot common.Hash) gosol.Mapping[common.Address, bool] {
+		return gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[common.Address, bool](storage, slot, gosol.AddressKey, gosol.BoolCodec{}))
+	})
+	m.Nonce = gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[common.Address, uint256.Int](storage, NONCE_SLOT, gosol.AddressKey, gosol.Uint256Codec{}))
+	m.IdToMarketParams = gosol.NewJournaledMapping(journal, gosol.NewStorageMapping[common.Hash, MarketParams](storage, ID_TO_MARKET_PARAMS_SLOT, gosol.HashKey, MarketParamsCodec{}))
+	return m, nil
+}
+
+// packUint128 packs two uint128 struct fields, failing like SafeCast.toUint128 if they do not fit
+func packUint128(low, high *uint256.Int) (common.Hash, error) {
+	if low.Gt(&MaxUint128) || high.Gt(&MaxUint128) {
+		return common.Hash{}, ErrorMaxUint128Exceeded
+	}
+	return gosol.PackUint128(low, high)
+}
@@ -0,0 +1,108 @@
+package morphoblue
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageCodecs(t *testing.T) {
+	market := Market{
+		TotalSupplyAssets: *uint256.MustFromDecimal("1000000000000000000000"),
+		TotalSupplyShares: *uint256.MustFromDecimal("1000000000000000000000000000"),
+		TotalBorrowAssets: *uint256.MustFromDecimal("500000000000000000000"),
+		TotalBorrowShares: *uint256.MustFromDecimal("500000000000000000000000000"),
+		LastUpdate:        *uint256.NewInt(1700000000),
+		Fee:               *uint256.MustFromDecimal("100000000000000000"),
+	}
+	words, err := MarketCodec{}.Encode(market)
+	require.NoError(t, err)
+	// totalSupplyShares is packed in the higher-order bytes of the first word
+	require.Equal(t, common.HexToHash("0x00000000033b2e3c9fd0803ce8000000000000000000003635c9adc5dea00000"), words[0])
+	decoded, err := MarketCodec{}.Decode(words)
+	require.NoError(t, err)
+	require.Equal(t, market, decoded)
+
+	market.Fee = *new(uint256.Int).AddUint64(&MaxUint128, 1)
+	_, err = MarketCodec{}.Encode(market)
+	require.Equal(t, ErrorMaxUint128Exceeded, err)
+
+	position := Position{
+		SupplyShares: *uint256.MustFromDecimal("1000000000000000000000000000"),
+		BorrowShares: *uint256.NewInt(2),
+		Collateral:   *uint256.NewInt(3),
+	}
+	words, err = PositionCodec{}.Encode(position)
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("0x0000000000000000000000000000000300000000000000000000000000000002"), words[1])
+	decodedPosition, err := PositionCodec{}.Decode(words)
+	require.NoError(t, err)
+	require.Equal(t, position, decodedPosition)
+}
+
+func TestStorageSlots(t *testing.T) {
+	id := common.HexToHash("0xb323495f7e4148be5643a4ea4a8221eef163e4bccfdedc2a6f4696baacbc86cc")
+	user := common.HexToAddress("0x5555555555555555555555555555555555555555")
+
+	// keccak256(user . keccak256(id . 2))
+	inner := crypto.Keccak256(id.Bytes(), common.BigToHash(common.Big2).Bytes())
+	expected := crypto.Keccak256Hash(common.LeftPadBytes(user.Bytes(), 32), inner)
+	require.Equal(t, expected, PositionSlot(id, user))
+	require.Equal(t, crypto.Keccak256Hash(id.Bytes(), common.BigToHash(common.Big3).Bytes()), MarketSlot(id))
+}
+
+func TestMorphoWithStorage(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	storage := gosol.MapStorage{
+		OWNER_SLOT:         gosol.AddressKey(owner),
+		FEE_RECIPIENT_SLOT: gosol.AddressKey(owner),
+	}
+	morpho, err := NewMorphoWithStorage(storage)
+	require.NoError(t, err)
+	require.Equal(t, owner, morpho.Owner)
+
+	irmAddr := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	require.NoError(t, morpho.Irms.Set(irmAddr, &mockIRM{rate: uint256.NewInt(0)}))
+	require.NoError(t, morpho.EnableIrm(owner, irmAddr))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	marketParams := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             irmAddr,
+		Lltv:            *lltv,
+	}
+	require.NoError(t, morpho.CreateMarket(owner, marketParams))
+	marketId := ComputeMarketId(marketParams)
+
+	supplyAmount := uint256.MustFromDecimal("1000000000000000000")
+	require.NoError(t, morpho.Deal(marketParams.LoanToken, supplier, supplyAmount))
+	_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+
+	// the raw storage decodes to the state of the contract
+	market, err := MarketCodec{}.Decode([]common.Hash{
+		storage[MarketSlot(marketId)],
+		storage[gosol.SlotOffset(MarketSlot(marketId), 1)],
+		storage[gosol.SlotOffset(MarketSlot(marketId), 2)],
+	})
+	require.NoError(t, err)
+	require.Equal(t, supplyAmount.String(), market.TotalSupplyAssets.String())
+	require.Equal(t, "1000000000000000000000000", market.TotalSupplyShares.String())
+	require.Equal(t, morpho.BlockTimestamp, market.LastUpdate.Uint64())
+
+	position := storage[PositionSlot(marketId, supplier)]
+	require.Equal(t, market.TotalSupplyShares.Bytes32(), [32]byte(position))
+	require.Equal(t, gosol.AddressKey(marketParams.Irm), storage[gosol.SlotOffset(MarketParamsSlot(marketId), 3)])
+
+	// failed operations revert the raw storage
+	_, _, err = morpho.Supply(supplier, uint256.NewInt(0), marketParams, supplyAmount, uint256.NewInt(0), supplier, nil)
+	require.Equal(t, ErrorTransferFromReverted, err)
+	require.Equal(t, position, storage[PositionSlot(marketId, supplier)])
+}
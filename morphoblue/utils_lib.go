@@ -0,0 +1,29 @@
+package morphoblue
+
+import (
+	"github.com/holiman/uint256"
+)
+
+// UtilsLib provides helpers mirroring the Solidity UtilsLib from Morpho Blue
+// https://github.com/morpho-org/morpho-blue/blob/main/src/libraries/UtilsLib.sol
+
+// ExactlyOneZero returns true if exactly one of x and y is zero
+func ExactlyOneZero(x, y *uint256.Int) bool {
+	return x.IsZero() != y.IsZero()
+}
+
+// Min returns the smaller of x and y
+func Min(z, x, y *uint256.Int) *uint256.Int {
+	if x.Lt(y) {
+		return z.Set(x)
+	}
+	return z.Set(y)
+}
+
+// ZeroFloorSub returns max(0, x - y)
+func ZeroFloorSub(z, x, y *uint256.Int) *uint256.Int {
+	if x.Lt(y) {
+		return z.Clear()
+	}
+	return z.Sub(x, y)
+}
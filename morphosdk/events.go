@@ -0,0 +1,308 @@
+package morphosdk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+	"github.com/holiman/uint256"
+)
+
+// ErrUnknownFeeRecipient is returned when fee shares are minted while the state has no fee recipient
+var ErrUnknownFeeRecipient = errors.New("unknown fee recipient")
+
+// ApplyEvents updates the markets, positions, user nonces and fee recipient of the state the same way Morpho Blue did
+// when it emitted events at the block timestamp of the state, either all events are applied or none.
+// events about state that is not held, like the owner, enabled IRMs and LLTVs or authorizations, are ignored.
+// the changed markets, positions and users are replaced rather than updated in place, keeping their other fields.
+// markets with a rate at target are assumed to use the AdaptiveCurveIrm, whose rate at target is updated
+// on market creations and interest accruals.
+func (s *InputSimulationState) ApplyEvents(events ...morphoblue.Event) error {
+	_, err := s.applyEvents(events)
+	return err
+}
+
+// applyEvents applies events like ApplyEvents, returning the undo record of the changes
+func (s *InputSimulationState) applyEvents(events []morphoblue.Event) (*stateUndo, error) {
+	r := &eventReplay{
+		state:         s,
+		markets:       map[common.Hash]*morphoblue.Market{},
+		params:        map[common.Hash]MarketParams{},
+		positions:     map[common.Hash]map[common.Address]*morphoblue.Position{},
+		nonces:        map[common.Address]uint256.Int{},
+		ratesAtTarget: map[common.Hash]uint256.Int{},
+	}
+	if s.Global != nil {
+		r.feeRecipient = s.Global.FeeRecipient
+	}
+	for _, event := range events {
+		if err := r.apply(event); err != nil {
+			return nil, fmt.Errorf("%s: %w", event.EventName(), err)
+		}
+	}
+	return r.commit(), nil
+}
+
+// eventReplay stages the changes of events to a state, so that they are only written once all of them are applied
+type eventReplay struct {
+	state        *InputSimulationState
+	feeRecipient *common.Address
+	markets      map[common.Hash]*morphoblue.Market
+	params       map[common.Hash]MarketParams
+	positions    map[common.Hash]map[common.Address]*morphoblue.Position
+	nonces       map[common.Address]uint256.Int
+	// ratesAtTarget holds the staged rates at target of the markets using the AdaptiveCurveIrm
+	ratesAtTarget map[common.Hash]uint256.Int
+}
+
+func (r *eventReplay) apply(event morphoblue.Event) error {
+	switch e := event.(type) {
+	case morphoblue.SetFeeRecipientEvent:
+		feeRecipient := e.NewFeeRecipient
+		r.feeRecipient = &feeRecipient
+		return nil
+	case morphoblue.IncrementNonceEvent:
+		r.nonces[e.Authorizer] = *new(uint256.Int).AddUint64(&e.UsedNonce, 1)
+		return nil
+	case morphoblue.SetOwnerEvent, morphoblue.EnableIrmEvent, morphoblue.EnableLltvEvent,
+		morphoblue.SetAuthorizationEvent, morphoblue.FlashLoanEvent:
+		return nil
+	case morphoblue.MarketEvent:
+		return r.applyMarketEvent(e)
+	}
+	return morphoblue.ErrorUnknownEvent
+}
+
+func (r *eventReplay) applyMarketEvent(event morphoblue.MarketEvent) error {
+	id := event.MarketId()
+	market := r.market(id)
+	if create, ok := event.(morphoblue.CreateMarketEvent); ok {
+		if !market.LastUpdate.IsZero() {
+			return morphoblue.ErrorMarketAlreadyCreated
+		}
+		r.params[id] = MarketParams{
+			LoanToken:       create.MarketParams.LoanToken,
+			CollateralToken: create.MarketParams.CollateralToken,
+			Oracle:          create.MarketParams.Oracle,
+			Irm:             create.MarketParams.Irm,
+			Lltv:            create.MarketParams.Lltv,
+		}
+	} else if market.LastUpdate.IsZero() {
+		return morphoblue.ErrorMarketNotCreated
+	}
+	var feeRecipient common.Address
+	if accrue, ok := event.(morphoblue.AccrueInterestEvent); ok && !accrue.FeeShares.IsZero() {
+		if r.feeRecipient == nil {
+			return ErrUnknownFeeRecipient
+		}
+		feeRecipient = *r.feeRecipient
+	}
+
+	// Morpho Blue called the IRM with the market before the event
+	var rateAtTarget *uint256.Int
+	switch event.(type) {
+	case morphoblue.CreateMarketEvent, morphoblue.AccrueInterestEvent:
+		if start := r.rateAtTarget(id); start != nil {
+			_, end, err := morphoblue.AdaptiveCurveBorrowRate(*market, start, r.state.Block.Timestamp.Uint64())
+			if err != nil {
+				return err
+			}
+			rateAtTarget = end
+		}
+	}
+
+	// the event is applied to copies, so that a failed event leaves the staged changes untouched
+	updated := *market
+	positions := map[common.Address]*morphoblue.Position{}
+	position := func(user common.Address) (*morphoblue.Position, error) {
+		if p, ok := positions[user]; ok {
+			return p, nil
+		}
+		p := r.position(id, user)
+		positions[user] = &p
+		return &p, nil
+	}
+	if err := morphoblue.ApplyMarketEvent(&updated, position, feeRecipient, r.state.Block.Timestamp.Uint64(), event); err != nil {
+		return err
+	}
+
+	r.markets[id] = &updated
+	if rateAtTarget != nil {
+		r.ratesAtTarget[id] = *rateAtTarget
+	}
+	if r.positions[id] == nil {
+		r.positions[id] = map[common.Address]*morphoblue.Position{}
+	}
+	for user, p := range positions {
+		r.positions[id][user] = p
+	}
+	return nil
+}
+
+// market returns the staged market, falling back to the state, which is zero if the market is not created
+func (r *eventReplay) market(id common.Hash) *morphoblue.Market {
+	if market, ok := r.markets[id]; ok {
+		return market
+	}
+	market, ok := r.state.Markets[id]
+	if !ok {
+		return &morphoblue.Market{}
+	}
+	return &morphoblue.Market{
+		TotalSupplyAssets: market.TotalSupplyAssets,
+		TotalSupplyShares: market.TotalSupplyShares,
+		TotalBorrowAssets: market.TotalBorrowAssets,
+		TotalBorrowShares: market.TotalBorrowShares,
+		LastUpdate:        market.LastUpdate,
+		Fee:               market.Fee,
+	}
+}
+
+// rateAtTarget returns a copy of the staged rate at target of the market, falling back to the state,
+// nil if the market does not use the AdaptiveCurveIrm
+func (r *eventReplay) rateAtTarget(id common.Hash) *uint256.Int {
+	if rateAtTarget, ok := r.ratesAtTarget[id]; ok {
+		return &rateAtTarget
+	}
+	market, ok := r.state.Markets[id]
+	if !ok || market == nil || market.RateAtTarget == nil {
+		return nil
+	}
+	return new(uint256.Int).Set(market.RateAtTarget)
+}
+
+// position returns a copy of the staged position, falling back to the state
+func (r *eventReplay) position(id common.Hash, user common.Address) morphoblue.Position {
+	if position, ok := r.positions[id][user]; ok {
+		return *position
+	}
+	position, ok := r.state.Positions[user][id]
+	if !ok {
+		return morphoblue.Position{}
+	}
+	return morphoblue.Position{
+		SupplyShares: position.SupplyShares,
+		BorrowShares: position.BorrowShares,
+		Collateral:   position.Collateral,
+	}
+}
+
+// commit writes the staged changes to the state, replacing the changed entries instead of updating them in place,
+// and returns the undo record restoring the replaced entries
+func (r *eventReplay) commit() *stateUndo {
+	s := r.state
+	undo := &stateUndo{
+		markets:   map[common.Hash]*Market{},
+		positions: map[common.Address]map[common.Hash]*Position{},
+		users:     map[common.Address]*User{},
+	}
+	if r.feeRecipient != nil {
+		undo.global, undo.globalChanged = s.Global, true
+		global := GlobalConfig{}
+		if s.Global != nil {
+			global = *s.Global
+		}
+		global.FeeRecipient = r.feeRecipient
+		s.Global = &global
+	}
+	for id, updated := range r.markets {
+		if s.Markets == nil {
+			s.Markets = map[common.Hash]*Market{}
+		}
+		market := Market{}
+		if prev, ok := s.Markets[id]; ok {
+			market = *prev
+		}
+		undo.markets[id] = s.Markets[id]
+		if params, ok := r.params[id]; ok {
+			market.Params = params
+		}
+		market.TotalSupplyAssets = updated.TotalSupplyAssets
+		market.TotalSupplyShares = updated.TotalSupplyShares
+		market.TotalBorrowAssets = updated.TotalBorrowAssets
+		market.TotalBorrowShares = updated.TotalBorrowShares
+		market.LastUpdate = updated.LastUpdate
+		market.Fee = updated.Fee
+		if rateAtTarget, ok := r.ratesAtTarget[id]; ok {
+			market.RateAtTarget = &rateAtTarget
+		}
+		s.Markets[id] = &market
+	}
+	for id, positions := range r.positions {
+		for user, updated := range positions {
+			if s.Positions == nil {
+				s.Positions = map[common.Address]map[common.Hash]*Position{}
+			}
+			if s.Positions[user] == nil {
+				s.Positions[user] = map[common.Hash]*Position{}
+			}
+			if undo.positions[user] == nil {
+				undo.positions[user] = map[common.Hash]*Position{}
+			}
+			undo.positions[user][id] = s.Positions[user][id]
+			s.Positions[user][id] = &Position{
+				User:         user,
+				MarketId:     id,
+				SupplyShares: updated.SupplyShares,
+				BorrowShares: updated.BorrowShares,
+				Collateral:   updated.Collateral,
+			}
+		}
+	}
+	for address, nonce := range r.nonces {
+		if s.Users == nil {
+			s.Users = map[common.Address]*User{}
+		}
+		user := User{Address: address}
+		if prev, ok := s.Users[address]; ok {
+			user = *prev
+		}
+		undo.users[address] = s.Users[address]
+		user.MorphoNonce = nonce
+		s.Users[address] = &user
+	}
+	return undo
+}
+
+// stateUndo holds the entries of a state replaced by applying events, nil for the entries which were missing
+type stateUndo struct {
+	global        *GlobalConfig
+	globalChanged bool
+	markets       map[common.Hash]*Market
+	positions     map[common.Address]map[common.Hash]*Position
+	users         map[common.Address]*User
+}
+
+// revert restores the replaced entries of s
+func (u *stateUndo) revert(s *InputSimulationState) {
+	if u.globalChanged {
+		s.Global = u.global
+	}
+	for id, market := range u.markets {
+		if market == nil {
+			delete(s.Markets, id)
+		} else {
+			s.Markets[id] = market
+		}
+	}
+	for user, positions := range u.positions {
+		for id, position := range positions {
+			if position == nil {
+				delete(s.Positions[user], id)
+			} else {
+				s.Positions[user][id] = position
+			}
+		}
+		if len(s.Positions[user]) == 0 {
+			delete(s.Positions, user)
+		}
+	}
+	for address, user := range u.users {
+		if user == nil {
+			delete(s.Users, address)
+		} else {
+			s.Users[address] = user
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package morphosdk
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedRateIRM struct {
+	rate *uint256.Int
+}
+
+func (irm *fixedRateIRM) BorrowRate(marketParams morphoblue.MarketParams, market morphoblue.Market) (*uint256.Int, error) {
+	return irm.rate, nil
+}
+
+type fixedPriceOracle struct {
+	price *uint256.Int
+}
+
+func (oracle *fixedPriceOracle) Price() (*uint256.Int, error) {
+	return oracle.price, nil
+}
+
+func TestApplyEvents(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	liquidator := common.HexToAddress("0x7777777777777777777777777777777777777777")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	params := morphoblue.MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *lltv,
+	}
+	id := morphoblue.ComputeMarketId(params)
+
+	morpho := morphoblue.NewMorpho(owner, owner)
+	oracle := &fixedPriceOracle{price: morphoblue.ORACLE_PRICE_SCALE}
+	require.NoError(t, morpho.Oracles.Set(params.Oracle, oracle))
+	require.NoError(t, morpho.Irms.Set(params.Irm, &fixedRateIRM{rate: uint256.NewInt(1000000000)}))
+	require.NoError(t, morpho.Deal(params.LoanToken, supplier, uint256.MustFromDecimal("1000000000000000000000")))
+	require.NoError(t, morpho.Deal(params.CollateralToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+	require.NoError(t, morpho.Deal(params.LoanToken, liquidator, uint256.MustFromDecimal("1000000000000000000000")))
+
+	require.NoError(t, morpho.EnableIrm(owner, params.Irm))
+	require.NoError(t, morpho.EnableLltv(owner, lltv))
+	require.NoError(t, morpho.CreateMarket(owner, params))
+	require.NoError(t, morpho.SetFeeRecipient(owner, feeRecipient))
+	require.NoError(t, morpho.SetFee(owner, params, uint256.MustFromDecimal("100000000000000000")))
+	_, _, err := morpho.Supply(supplier, uint256.NewInt(0), params, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+	require.NoError(t, err)
+	require.NoError(t, morpho.SupplyCollateral(borrower, uint256.NewInt(0), params, uint256.MustFromDecimal("100000000000000000000"), borrower, nil))
+	_, _, err = morpho.Borrow(borrower, params, uint256.MustFromDecimal("80000000000000000000"), uint256.NewInt(0), borrower, borrower)
+	require.NoError(t, err)
+
+	state := InputSimulationState{}
+	state.Block.Timestamp.SetUint64(morpho.BlockTimestamp)
+	require.NoError(t, state.ApplyEvents(morpho.Logs...))
+	emitted := len(morpho.Logs)
+
+	// a year later, the fee is accrued and the liquidation of all the collateral realizes bad debt
+	morpho.BlockTimestamp += 31536000
+	oracle.price = uint256.MustFromDecimal("500000000000000000000000000000000000")
+	_, _, err = morpho.Liquidate(liquidator, uint256.NewInt(0), params, borrower, uint256.MustFromDecimal("100000000000000000000"), uint256.NewInt(0), nil)
+	require.NoError(t, err)
+
+	state.Block.Timestamp.SetUint64(morpho.BlockTimestamp)
+	require.NoError(t, state.ApplyEvents(morpho.Logs[emitted:]...))
+
+	require.Equal(t, feeRecipient, *state.Global.FeeRecipient)
+	market, err := morpho.Market.Get(id)
+	require.NoError(t, err)
+	replayed := state.Markets[id]
+	require.Equal(t, ComputeMarketId(replayed.Params), id)
+	require.Equal(t, market, morphoblue.Market{
+		TotalSupplyAssets: replayed.TotalSupplyAssets,
+		TotalSupplyShares: replayed.TotalSupplyShares,
+		TotalBorrowAssets: replayed.TotalBorrowAssets,
+		TotalBorrowShares: replayed.TotalBorrowShares,
+		LastUpdate:        replayed.LastUpdate,
+		Fee:               replayed.Fee,
+	})
+
+	positions, err := morpho.Position.Get(id)
+	require.NoError(t, err)
+	for _, user := range []common.Address{feeRecipient, supplier, borrower} {
+		position, err := positions.Get(user)
+		require.NoError(t, err)
+		replayed := state.Positions[user][id]
+		require.Equal(t, position, morphoblue.Position{
+			SupplyShares: replayed.SupplyShares,
+			BorrowShares: replayed.BorrowShares,
+			Collateral:   replayed.Collateral,
+		}, user.Hex())
+	}
+	require.False(t, state.Positions[feeRecipient][id].SupplyShares.IsZero())
+
+	t.Run("Failed events are not applied", func(t *testing.T) {
+		before := *state.Markets[id]
+		err := state.ApplyEvents(
+			morphoblue.SupplyEvent{Id: id, OnBehalf: supplier, Assets: *uint256.NewInt(1), Shares: *uint256.NewInt(1)},
+			morphoblue.BorrowEvent{Id: common.Hash{1}, OnBehalf: supplier},
+		)
+		require.ErrorIs(t, err, morphoblue.ErrorMarketNotCreated)
+		require.Equal(t, before, *state.Markets[id])
+
+		unknown := InputSimulationState{Markets: state.Markets}
+		err = unknown.ApplyEvents(morphoblue.AccrueInterestEvent{Id: id, FeeShares: *uint256.NewInt(1)})
+		require.ErrorIs(t, err, ErrUnknownFeeRecipient)
+	})
+}
+
+func TestApplyEventsAdaptiveCurveIrm(t *testing.T) {
+	owner := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	lltv := uint256.MustFromDecimal("800000000000000000")
+	params := morphoblue.MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *lltv,
+	}
+	id := morphoblue.ComputeMarketId(params)
+
+	morpho := morphoblue.NewMorpho(owner, owner)
+	irm := morphoblue.NewAdaptiveCurveIrm(morpho)
+	require.NoError(t, morpho.Oracles.Set(params.Oracle, &fixedPriceOracle{price: morphoblue.ORACLE_PRICE_SCALE}))
+	require.NoError(t, morpho.Irms.Set(params.Irm, irm))
+	require.NoError(t, morpho.Deal(params.LoanToken, supplier, uint256.MustFromDecimal("2000000000000000000000")))
+	require.NoError(t, morpho.Deal(params.CollateralToken, borrower, uint256.MustFromDecimal("100000000000000000000")))
+
+	// a zero rate at target marks the market as using the AdaptiveCurveIrm before it is created
+	state := InputSimulationState{
+		Markets: map[common.Hash]*Market{id: {Params: MarketParams{Irm: params.Irm}, RateAtTarget: new(uint256.Int)}},
+	}
+	block := func(run func()) {
+		emitted := len(morpho.Logs)
+		run()
+		state.Block.Timestamp.SetUint64(morpho.BlockTimestamp)
+		require.NoError(t, state.ApplyEvents(morpho.Logs[emitted:]...))
+		morpho.BlockTimestamp += 86400
+	}
+	block(func() {
+		require.NoError(t, morpho.EnableIrm(owner, params.Irm))
+		require.NoError(t, morpho.EnableLltv(owner, lltv))
+		require.NoError(t, morpho.CreateMarket(owner, params))
+		_, _, err := morpho.Supply(supplier, uint256.NewInt(0), params, uint256.MustFromDecimal("1000000000000000000000"), uint256.NewInt(0), supplier, nil)
+		require.NoError(t, err)
+		require.NoError(t, morpho.SupplyCollateral(borrower, uint256.NewInt(0), params, uint256.MustFromDecimal("100000000000000000000"), borrower, nil))
+		_, _, err = morpho.Borrow(borrower, params, uint256.MustFromDecimal("75000000000000000000"), uint256.NewInt(0), borrower, borrower)
+		require.NoError(t, err)
+	})
+	for i := 0; i < 2; i++ {
+		block(func() {
+			_, _, err := morpho.Supply(supplier, uint256.NewInt(0), params, uint256.MustFromDecimal("100000000000000000000"), uint256.NewInt(0), supplier, nil)
+			require.NoError(t, err)
+		})
+	}
+
+	rateAtTarget, err := irm.RateAtTarget.Get(id)
+	require.NoError(t, err)
+	require.NotEqual(t, morphoblue.AdaptiveIRM.INITIAL_RATE_AT_TARGET.String(), rateAtTarget.String())
+	replayed := state.Markets[id]
+	require.Equal(t, rateAtTarget.String(), replayed.RateAtTarget.String())
+	market, err := morpho.Market.Get(id)
+	require.NoError(t, err)
+	require.Equal(t, market.TotalBorrowAssets.String(), replayed.TotalBorrowAssets.String())
+
+	// markets without a rate at target are left without one
+	state.Markets[id].RateAtTarget = nil
+	state.Block.Timestamp.AddUint64(&state.Block.Timestamp, 86400)
+	require.NoError(t, state.ApplyEvents(morphoblue.AccrueInterestEvent{Id: id}))
+	require.Nil(t, state.Markets[id].RateAtTarget)
+}
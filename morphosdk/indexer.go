@@ -0,0 +1,122 @@
+package morphosdk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+)
+
+var (
+	// ErrNonContiguousBlock is returned when a block does not extend the head of the indexer
+	ErrNonContiguousBlock = errors.New("non contiguous block")
+	// ErrReorgTooDeep is returned when rolling back more blocks than the undo records kept
+	ErrReorgTooDeep = errors.New("reorg too deep")
+)
+
+// IndexedBlock is a block with the logs it emitted, in log index order
+type IndexedBlock struct {
+	Block      MinimalBlock
+	Hash       common.Hash
+	ParentHash common.Hash
+	Logs       []types.Log
+}
+
+// blockUndo restores the state to before a block was applied
+type blockUndo struct {
+	block MinimalBlock
+	hash  common.Hash
+	state *stateUndo
+}
+
+// Indexer maintains a state from the Morpho Blue logs of consecutive blocks, keeping undo records of the
+// last blocks so that reorgs can be rolled back. it is not safe for concurrent use
+type Indexer struct {
+	// Morpho is the address of Morpho Blue, the logs of other contracts are skipped
+	Morpho common.Address
+	// State is the indexed state, its Block is the last applied block
+	State *InputSimulationState
+
+	head  common.Hash
+	depth int
+	undo  []blockUndo
+}
+
+// NewIndexer returns an indexer extending state, whose head has the given hash, zero if unknown to accept any next block.
+// undo records are kept for the last depth blocks, the deepest reorg which can be rolled back, none if depth is not positive
+func NewIndexer(morpho common.Address, state *InputSimulationState, head common.Hash, depth int) *Indexer {
+	if depth < 0 {
+		depth = 0
+	}
+	return &Indexer{
+		Morpho: morpho,
+		State:  state,
+		head:   head,
+		depth:  depth,
+	}
+}
+
+// Head returns the hash of the last applied block
+func (ix *Indexer) Head() common.Hash {
+	return ix.head
+}
+
+// Depth returns the number of blocks which can be rolled back
+func (ix *Indexer) Depth() int {
+	return len(ix.undo)
+}
+
+// Apply applies the Morpho Blue logs of block, which must be the child of the head.
+// either the whole block is applied or the state is left unchanged
+func (ix *Indexer) Apply(block IndexedBlock) error {
+	next := ix.State.Block.Number.Uint64() + 1
+	if ix.head != (common.Hash{}) && (block.ParentHash != ix.head || block.Block.Number.Uint64() != next) {
+		return fmt.Errorf("%w: block %d %s does not extend %d %s",
+			ErrNonContiguousBlock, block.Block.Number.Uint64(), block.Hash, next-1, ix.head)
+	}
+
+	var events []morphoblue.Event
+	for _, log := range block.Logs {
+		if log.Address != ix.Morpho || log.Removed {
+			continue
+		}
+		event, err := morphoblue.DecodeLog(log)
+		if err != nil {
+			return fmt.Errorf("block %d log %d: %w", block.Block.Number.Uint64(), log.Index, err)
+		}
+		events = append(events, event)
+	}
+
+	prev := ix.State.Block
+	ix.State.Block = block.Block
+	undo, err := ix.State.applyEvents(events)
+	if err != nil {
+		ix.State.Block = prev
+		return fmt.Errorf("block %d: %w", block.Block.Number.Uint64(), err)
+	}
+
+	ix.undo = append(ix.undo, blockUndo{block: prev, hash: ix.head, state: undo})
+	if len(ix.undo) > ix.depth {
+		ix.undo = ix.undo[len(ix.undo)-ix.depth:]
+	}
+	ix.head = block.Hash
+	return nil
+}
+
+// Rollback reverts the last n applied blocks, after a reorg is signalled.
+// it fails with ErrReorgTooDeep, leaving the state unchanged, if fewer blocks can be rolled back
+func (ix *Indexer) Rollback(n int) error {
+	if n > len(ix.undo) {
+		return fmt.Errorf("%w: %d blocks, at most %d", ErrReorgTooDeep, n, len(ix.undo))
+	}
+	for ; n > 0; n-- {
+		last := ix.undo[len(ix.undo)-1]
+		last.state.revert(ix.State)
+		ix.State.Block = last.block
+		ix.head = last.hash
+		ix.undo = ix.undo[:len(ix.undo)-1]
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package morphosdk
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexer(t *testing.T) {
+	morpho := common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	supplier := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	borrower := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	params := morphoblue.MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *uint256.MustFromDecimal("860000000000000000"),
+	}
+	id := morphoblue.ComputeMarketId(params)
+
+	// block returns the block number on top of parent, emitting events at morpho
+	block := func(number uint64, hash, parent common.Hash, events ...morphoblue.Event) IndexedBlock {
+		b := IndexedBlock{Hash: hash, ParentHash: parent}
+		b.Block.Number.SetUint64(number)
+		b.Block.Timestamp.SetUint64(1700000000 + number*12)
+		for i, event := range events {
+			log, err := morphoblue.EncodeLog(morpho, event)
+			require.NoError(t, err)
+			log.Index = uint(i)
+			b.Logs = append(b.Logs, log)
+		}
+		return b
+	}
+	snapshot := func(state *InputSimulationState) string {
+		encoded, err := json.Marshal(state)
+		require.NoError(t, err)
+		return string(encoded)
+	}
+
+	genesis := common.Hash{0x0a}
+	state := &InputSimulationState{}
+	state.Block.Number.SetUint64(100)
+	indexer := NewIndexer(morpho, state, genesis, 2)
+	initial := snapshot(state)
+
+	a101 := block(101, common.Hash{0xa1}, genesis,
+		morphoblue.CreateMarketEvent{Id: id, MarketParams: params},
+		morphoblue.SetFeeRecipientEvent{NewFeeRecipient: feeRecipient},
+		morphoblue.SupplyEvent{Id: id, OnBehalf: supplier, Assets: *uint256.NewInt(1000), Shares: *uint256.NewInt(1000000000)},
+	)
+	// logs of other contracts are skipped
+	a101.Logs = append(a101.Logs, types.Log{Address: common.Address{1}, Topics: []common.Hash{{1}}})
+	require.NoError(t, indexer.Apply(a101))
+	require.Equal(t, common.Hash{0xa1}, indexer.Head())
+	require.Equal(t, uint64(101), state.Block.Number.Uint64())
+	require.Equal(t, a101.Block.Timestamp, state.Markets[id].LastUpdate)
+	afterA101 := snapshot(state)
+
+	require.NoError(t, indexer.Apply(block(102, common.Hash{0xa2}, common.Hash{0xa1},
+		morphoblue.SupplyCollateralEvent{Id: id, OnBehalf: borrower, Assets: *uint256.NewInt(1000)},
+		morphoblue.BorrowEvent{Id: id, OnBehalf: borrower, Assets: *uint256.NewInt(500), Shares: *uint256.NewInt(500000000)},
+	)))
+	require.NoError(t, indexer.Apply(block(103, common.Hash{0xa3}, common.Hash{0xa2},
+		morphoblue.AccrueInterestEvent{Id: id, Interest: *uint256.NewInt(10), FeeShares: *uint256.NewInt(1000000)},
+		morphoblue.RepayEvent{Id: id, OnBehalf: borrower, Assets: *uint256.NewInt(100), Shares: *uint256.NewInt(98000000)},
+	)))
+	require.Equal(t, "1010", state.Markets[id].TotalSupplyAssets.String())
+	require.Equal(t, "410", state.Markets[id].TotalBorrowAssets.String())
+	require.Equal(t, "1000000", state.Positions[feeRecipient][id].SupplyShares.String())
+	// only the last two blocks can be rolled back
+	require.Equal(t, 2, indexer.Depth())
+
+	t.Run("Blocks must extend the head", func(t *testing.T) {
+		before := snapshot(state)
+		err := indexer.Apply(block(104, common.Hash{0xb4}, common.Hash{0xb3}))
+		require.ErrorIs(t, err, ErrNonContiguousBlock)
+		err = indexer.Apply(block(105, common.Hash{0xa5}, common.Hash{0xa3}))
+		require.ErrorIs(t, err, ErrNonContiguousBlock)
+		// a failing event leaves the state and head unchanged
+		err = indexer.Apply(block(104, common.Hash{0xa4}, common.Hash{0xa3},
+			morphoblue.SupplyEvent{Id: id, OnBehalf: supplier, Assets: *uint256.NewInt(1), Shares: *uint256.NewInt(1)},
+			morphoblue.WithdrawEvent{Id: id, OnBehalf: borrower, Assets: *uint256.NewInt(1), Shares: *uint256.NewInt(1)},
+		))
+		require.ErrorIs(t, err, morphoblue.ErrorUint256Underflow)
+		require.Equal(t, before, snapshot(state))
+		require.Equal(t, common.Hash{0xa3}, indexer.Head())
+	})
+
+	t.Run("Reorgs are rolled back", func(t *testing.T) {
+		require.ErrorIs(t, indexer.Rollback(3), ErrReorgTooDeep)
+		require.Equal(t, common.Hash{0xa3}, indexer.Head())
+
+		require.NoError(t, indexer.Rollback(2))
+		require.Equal(t, afterA101, snapshot(state))
+		require.Equal(t, common.Hash{0xa1}, indexer.Head())
+		require.Equal(t, 0, indexer.Depth())
+
+		// the fork replaces the rolled back blocks
+		require.NoError(t, indexer.Apply(block(102, common.Hash{0xb2}, common.Hash{0xa1},
+			morphoblue.WithdrawEvent{Id: id, OnBehalf: supplier, Assets: *uint256.NewInt(400), Shares: *uint256.NewInt(400000000)},
+		)))
+		require.Equal(t, "600", state.Markets[id].TotalSupplyAssets.String())
+		require.NotContains(t, state.Positions, borrower)
+		require.NotContains(t, state.Positions, feeRecipient)
+
+		require.NoError(t, indexer.Rollback(1))
+		require.Equal(t, afterA101, snapshot(state))
+	})
+
+	t.Run("Rolling back the creation of a market removes it", func(t *testing.T) {
+		state := &InputSimulationState{}
+		state.Block.Number.SetUint64(100)
+		indexer := NewIndexer(morpho, state, genesis, 2)
+		require.NoError(t, indexer.Apply(a101))
+		require.NoError(t, indexer.Rollback(1))
+		require.Equal(t, initial, snapshot(state))
+		require.Empty(t, state.Markets)
+		require.Empty(t, state.Positions)
+		require.Nil(t, state.Global)
+		require.Equal(t, genesis, indexer.Head())
+	})
+
+	t.Run("A negative depth keeps no undo records", func(t *testing.T) {
+		state := &InputSimulationState{}
+		state.Block.Number.SetUint64(100)
+		indexer := NewIndexer(morpho, state, genesis, -1)
+		require.NoError(t, indexer.Apply(a101))
+		require.Equal(t, 0, indexer.Depth())
+		require.ErrorIs(t, indexer.Rollback(1), ErrReorgTooDeep)
+	})
+}
@@ -0,0 +1,73 @@
+package morphosdk
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+)
+
+// StateOverride returns the eth_call state override making the Morpho contract deployed at morpho match
+// the markets, positions and user nonces of the state, as well as the fee recipient if it is set.
+// bundler authorizations are not included since the state does not hold the bundler address.
+// nil markets, positions and users are skipped.
+func (s *InputSimulationState) StateOverride(morpho common.Address) (map[common.Address]gethclient.OverrideAccount, error) {
+	target, storage, err := morphoblue.NewStorageOverride()
+	if err != nil {
+		return nil, err
+	}
+	if s.Global != nil && s.Global.FeeRecipient != nil {
+		storage[morphoblue.FEE_RECIPIENT_SLOT] = gosol.AddressKey(*s.Global.FeeRecipient)
+	}
+	for id, market := range s.Markets {
+		if market == nil {
+			continue
+		}
+		if err := target.Market.Set(id, morphoblue.Market{
+			TotalSupplyAssets: market.TotalSupplyAssets,
+			TotalSupplyShares: market.TotalSupplyShares,
+			TotalBorrowAssets: market.TotalBorrowAssets,
+			TotalBorrowShares: market.TotalBorrowShares,
+			LastUpdate:        market.LastUpdate,
+			Fee:               market.Fee,
+		}); err != nil {
+			return nil, err
+		}
+		if err := target.IdToMarketParams.Set(id, morphoblue.MarketParams{
+			LoanToken:       market.Params.LoanToken,
+			CollateralToken: market.Params.CollateralToken,
+			Oracle:          market.Params.Oracle,
+			Irm:             market.Params.Irm,
+			Lltv:            market.Params.Lltv,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	for user, positions := range s.Positions {
+		for id, position := range positions {
+			if position == nil {
+				continue
+			}
+			inner, err := target.Position.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			if err := inner.Set(user, morphoblue.Position{
+				SupplyShares: position.SupplyShares,
+				BorrowShares: position.BorrowShares,
+				Collateral:   position.Collateral,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for address, user := range s.Users {
+		if user == nil {
+			continue
+		}
+		if err := target.Nonce.Set(address, user.MorphoNonce); err != nil {
+			return nil, err
+		}
+	}
+	return morphoblue.NewStateOverride(morpho, storage), nil
+}
@@ -0,0 +1,108 @@
+package morphosdk
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gfx-labs/go-blue-sdk/gosol"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateOverride(t *testing.T) {
+	morpho := common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	user := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	params := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *uint256.MustFromDecimal("860000000000000000"),
+	}
+	marketId := ComputeMarketId(params)
+	state := InputSimulationState{
+		Global: &GlobalConfig{FeeRecipient: &feeRecipient},
+		Markets: map[common.Hash]*Market{
+			marketId: {
+				Params:            params,
+				TotalSupplyAssets: *uint256.MustFromDecimal("1000000000000000000000"),
+				TotalSupplyShares: *uint256.MustFromDecimal("1000000000000000000000000000"),
+				TotalBorrowAssets: *uint256.MustFromDecimal("500000000000000000000"),
+				TotalBorrowShares: *uint256.MustFromDecimal("500000000000000000000000000"),
+				LastUpdate:        *uint256.NewInt(1700000000),
+				Fee:               *uint256.MustFromDecimal("100000000000000000"),
+			},
+		},
+		Positions: map[common.Address]map[common.Hash]*Position{
+			user: {
+				marketId: {
+					User:         user,
+					MarketId:     marketId,
+					SupplyShares: *uint256.MustFromDecimal("1000000000000000000000"),
+					BorrowShares: *uint256.MustFromDecimal("2000000000000000000000"),
+					Collateral:   *uint256.MustFromDecimal("3000000000000000000"),
+				},
+			},
+		},
+		Users: map[common.Address]*User{
+			user: {Address: user, MorphoNonce: *uint256.NewInt(7)},
+		},
+	}
+
+	override, err := state.StateOverride(morpho)
+	require.NoError(t, err)
+	storage := gosol.MapStorage(override[morpho].StateDiff)
+	// the owner is not part of the state, so it is not overridden
+	require.NotContains(t, storage, morphoblue.OWNER_SLOT)
+
+	decoded, err := morphoblue.NewMorphoWithStorage(storage)
+	require.NoError(t, err)
+	require.Equal(t, feeRecipient, decoded.FeeRecipient)
+
+	market := state.Markets[marketId]
+	decodedMarket, err := decoded.Market.Get(marketId)
+	require.NoError(t, err)
+	require.Equal(t, morphoblue.Market{
+		TotalSupplyAssets: market.TotalSupplyAssets,
+		TotalSupplyShares: market.TotalSupplyShares,
+		TotalBorrowAssets: market.TotalBorrowAssets,
+		TotalBorrowShares: market.TotalBorrowShares,
+		LastUpdate:        market.LastUpdate,
+		Fee:               market.Fee,
+	}, decodedMarket)
+	decodedParams, err := decoded.IdToMarketParams.Get(marketId)
+	require.NoError(t, err)
+	require.Equal(t, ComputeMarketId(params), morphoblue.ComputeMarketId(decodedParams))
+
+	positions, err := decoded.Position.Get(marketId)
+	require.NoError(t, err)
+	decodedPosition, err := positions.Get(user)
+	require.NoError(t, err)
+	position := state.Positions[user][marketId]
+	require.Equal(t, morphoblue.Position{
+		SupplyShares: position.SupplyShares,
+		BorrowShares: position.BorrowShares,
+		Collateral:   position.Collateral,
+	}, decodedPosition)
+	nonce, err := decoded.Nonce.Get(user)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), nonce.Uint64())
+
+	t.Run("Nil entries are skipped", func(t *testing.T) {
+		other := common.HexToAddress("0x6666666666666666666666666666666666666666")
+		state := InputSimulationState{
+			Markets:   map[common.Hash]*Market{marketId: nil},
+			Positions: map[common.Address]map[common.Hash]*Position{user: {marketId: nil}},
+			Users:     map[common.Address]*User{other: nil},
+		}
+		override, err := state.StateOverride(morpho)
+		require.NoError(t, err)
+		decoded, err := morphoblue.NewMorphoWithStorage(gosol.MapStorage(override[morpho].StateDiff))
+		require.NoError(t, err)
+		decodedMarket, err := decoded.Market.Get(marketId)
+		require.NoError(t, err)
+		require.Equal(t, morphoblue.Market{}, decodedMarket)
+	})
+}
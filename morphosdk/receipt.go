@@ -0,0 +1,204 @@
+package morphosdk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+	"github.com/holiman/uint256"
+)
+
+// ErrReceiptBlockMismatch is returned when a receipt was not included in the block it is applied at
+var ErrReceiptBlockMismatch = errors.New("receipt block mismatch")
+
+var (
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+)
+
+// ReceiptContracts are the addresses of the contracts whose logs and allowances ApplyReceipt tracks, zero if not tracked
+type ReceiptContracts struct {
+	Morpho  common.Address
+	Bundler common.Address
+	Permit2 common.Address
+}
+
+// spender returns the allowance recipient of spender, if it is tracked
+func (c ReceiptContracts) spender(spender common.Address) (Erc20AllowanceRecipient, bool) {
+	switch {
+	case spender == (common.Address{}):
+		return "", false
+	case spender == c.Morpho:
+		return Erc20AllowanceRecipientMorpho, true
+	case spender == c.Bundler:
+		return Erc20AllowanceRecipientBundler, true
+	case spender == c.Permit2:
+		return Erc20AllowanceRecipientPermit2, true
+	}
+	return "", false
+}
+
+// ApplyReceipt updates the state in place with the logs of receipt, emitted at block, which becomes the block of the state.
+// either the whole receipt is applied or the state is left unchanged. it fails with ErrReceiptBlockMismatch if the
+// block number of the receipt is set and differs from the one of block.
+//   - Morpho Blue events update the markets and positions, like ApplyEvents
+//   - ERC20 Transfer and Approval events update the balances and allowances of the holdings in the state,
+//     missing holdings are not created since their balance is unknown, and allowances spent without an Approval are missed
+//   - the same Transfer events of vaults update the shares of their vault users in the state, including the shares
+//     minted and burned from the zero address, which covers deposits, withdrawals and fee shares alike
+func (s *InputSimulationState) ApplyReceipt(receipt *types.Receipt, block MinimalBlock, contracts ReceiptContracts) error {
+	if receipt.BlockNumber != nil && receipt.BlockNumber.Cmp(block.Number.ToBig()) != 0 {
+		return fmt.Errorf("%w: receipt of block %s applied at block %s", ErrReceiptBlockMismatch, receipt.BlockNumber, &block.Number)
+	}
+	r := &receiptReplay{
+		state:      s,
+		contracts:  contracts,
+		holdings:   map[common.Address]map[common.Address]*Holding{},
+		vaultUsers: map[common.Address]map[common.Address]*VaultUser{},
+	}
+	var events []morphoblue.Event
+	for _, log := range receipt.Logs {
+		if log.Removed {
+			continue
+		}
+		if log.Address == contracts.Morpho && contracts.Morpho != (common.Address{}) {
+			event, err := morphoblue.DecodeLog(*log)
+			if err != nil {
+				return fmt.Errorf("log %d: %w", log.Index, err)
+			}
+			events = append(events, event)
+			continue
+		}
+		if err := r.apply(log); err != nil {
+			return fmt.Errorf("log %d: %w", log.Index, err)
+		}
+	}
+	prev := s.Block
+	s.Block = block
+	if err := s.ApplyEvents(events...); err != nil {
+		s.Block = prev
+		return err
+	}
+	r.commit()
+	return nil
+}
+
+// receiptReplay stages the changes of token and vault logs to a state, so that they are only written once all of them are applied
+type receiptReplay struct {
+	state      *InputSimulationState
+	contracts  ReceiptContracts
+	holdings   map[common.Address]map[common.Address]*Holding
+	vaultUsers map[common.Address]map[common.Address]*VaultUser
+}
+
+func (r *receiptReplay) apply(log *types.Log) error {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	switch {
+	case log.Topics[0] == transferTopic && len(log.Topics) == 3 && len(log.Data) == 32:
+		from, to := common.BytesToAddress(log.Topics[1].Bytes()), common.BytesToAddress(log.Topics[2].Bytes())
+		value := new(uint256.Int).SetBytes(log.Data)
+		if err := r.transfer(log.Address, from, to, value); err != nil {
+			return err
+		}
+		return r.transferShares(log.Address, from, to, value)
+	case log.Topics[0] == approvalTopic && len(log.Topics) == 3 && len(log.Data) == 32:
+		owner, spender := common.BytesToAddress(log.Topics[1].Bytes()), common.BytesToAddress(log.Topics[2].Bytes())
+		recipient, ok := r.contracts.spender(spender)
+		if !ok {
+			return nil
+		}
+		if holding := r.holding(owner, log.Address); holding != nil {
+			// the allowances are copied, since the staged holding shares them with the state
+			allowances := make(map[Erc20AllowanceRecipient]uint256.Int, len(holding.Erc20Allowances)+1)
+			for recipient, allowance := range holding.Erc20Allowances {
+				allowances[recipient] = allowance
+			}
+			allowances[recipient] = *new(uint256.Int).SetBytes(log.Data)
+			holding.Erc20Allowances = allowances
+		}
+	}
+	return nil
+}
+
+// transfer moves value of token between the holdings of from and to, if they are in the state
+func (r *receiptReplay) transfer(token, from, to common.Address, value *uint256.Int) error {
+	if holding := r.holding(from, token); holding != nil {
+		if _, underflow := holding.Balance.SubOverflow(&holding.Balance, value); underflow {
+			return morphoblue.ErrorUint256Underflow
+		}
+	}
+	if holding := r.holding(to, token); holding != nil {
+		if _, overflow := holding.Balance.AddOverflow(&holding.Balance, value); overflow {
+			return morphoblue.ErrorUint256Overflow
+		}
+	}
+	return nil
+}
+
+// transferShares moves value shares of vault between its users from and to, if they are in the state,
+// the zero address minting and burning them
+func (r *receiptReplay) transferShares(vault, from, to common.Address, value *uint256.Int) error {
+	if user := r.vaultUser(vault, from); user != nil {
+		if _, underflow := user.Shares.SubOverflow(&user.Shares, value); underflow {
+			return morphoblue.ErrorUint256Underflow
+		}
+	}
+	if user := r.vaultUser(vault, to); user != nil {
+		if _, overflow := user.Shares.AddOverflow(&user.Shares, value); overflow {
+			return morphoblue.ErrorUint256Overflow
+		}
+	}
+	return nil
+}
+
+// holding returns the staged copy of the holding of token by user, nil if it is not in the state
+func (r *receiptReplay) holding(user, token common.Address) *Holding {
+	if holding, ok := r.holdings[user][token]; ok {
+		return holding
+	}
+	holding, ok := r.state.Holdings[user][token]
+	if !ok || holding == nil {
+		return nil
+	}
+	staged := *holding
+	if r.holdings[user] == nil {
+		r.holdings[user] = map[common.Address]*Holding{}
+	}
+	r.holdings[user][token] = &staged
+	return &staged
+}
+
+// vaultUser returns the staged copy of the user of vault, nil if it is not in the state
+func (r *receiptReplay) vaultUser(vault, address common.Address) *VaultUser {
+	if user, ok := r.vaultUsers[vault][address]; ok {
+		return user
+	}
+	user, ok := r.state.VaultUsers[vault][address]
+	if !ok || user == nil {
+		return nil
+	}
+	staged := *user
+	if r.vaultUsers[vault] == nil {
+		r.vaultUsers[vault] = map[common.Address]*VaultUser{}
+	}
+	r.vaultUsers[vault][address] = &staged
+	return &staged
+}
+
+func (r *receiptReplay) commit() {
+	for user, holdings := range r.holdings {
+		for token, holding := range holdings {
+			r.state.Holdings[user][token] = holding
+		}
+	}
+	for vault, users := range r.vaultUsers {
+		for address, user := range users {
+			r.state.VaultUsers[vault][address] = user
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package morphosdk
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gfx-labs/go-blue-sdk/morphoblue"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyReceipt(t *testing.T) {
+	contracts := ReceiptContracts{
+		Morpho:  common.HexToAddress("0xBBBBBbbBBb9cC5e90e3b3Af64bdAF62C37EEFFCb"),
+		Bundler: common.HexToAddress("0x8888888888888888888888888888888888888888"),
+	}
+	user := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	other := common.HexToAddress("0x6666666666666666666666666666666666666666")
+	vault := common.HexToAddress("0x9999999999999999999999999999999999999999")
+	feeRecipient := common.HexToAddress("0x0987654321098765432109876543210987654321")
+	params := MarketParams{
+		LoanToken:       common.HexToAddress("0x3333333333333333333333333333333333333333"),
+		CollateralToken: common.HexToAddress("0x4444444444444444444444444444444444444444"),
+		Oracle:          common.HexToAddress("0x2222222222222222222222222222222222222222"),
+		Irm:             common.HexToAddress("0x1111111111111111111111111111111111111111"),
+		Lltv:            *uint256.MustFromDecimal("860000000000000000"),
+	}
+	id := ComputeMarketId(params)
+
+	newState := func() *InputSimulationState {
+		state := &InputSimulationState{
+			Markets: map[common.Hash]*Market{
+				id: {Params: params, LastUpdate: *uint256.NewInt(1700000000)},
+			},
+			Holdings: map[common.Address]map[common.Address]*Holding{
+				user: {
+					params.LoanToken: {
+						User:            user,
+						Token:           params.LoanToken,
+						Balance:         *uint256.NewInt(1000),
+						Erc20Allowances: map[Erc20AllowanceRecipient]uint256.Int{Erc20AllowanceRecipientBundler: *uint256.NewInt(5)},
+					},
+				},
+			},
+			VaultUsers: map[common.Address]map[common.Address]*VaultUser{
+				vault: {
+					user:         {Address: user, Vault: vault, Shares: *uint256.NewInt(10)},
+					other:        {Address: other, Vault: vault},
+					feeRecipient: {Address: feeRecipient, Vault: vault},
+				},
+			},
+		}
+		state.Block.Number.SetUint64(1000)
+		state.Block.Timestamp.SetUint64(1700000000)
+		return state
+	}
+	var block MinimalBlock
+	block.Number.SetUint64(1001)
+	block.Timestamp.SetUint64(1700000012)
+	// the ERC4626 events, which do not change the shares since they come with a Transfer
+	depositTopic := crypto.Keccak256Hash([]byte("Deposit(address,address,uint256,uint256)"))
+	withdrawTopic := crypto.Keccak256Hash([]byte("Withdraw(address,address,address,uint256,uint256)"))
+	word := func(value uint64) []byte {
+		return common.BigToHash(uint256.NewInt(value).ToBig()).Bytes()
+	}
+	topic := func(address common.Address) common.Hash {
+		return common.BytesToHash(address.Bytes())
+	}
+	supply, err := morphoblue.EncodeLog(contracts.Morpho, morphoblue.SupplyEvent{
+		Id: id, Caller: contracts.Bundler, OnBehalf: user, Assets: *uint256.NewInt(400), Shares: *uint256.NewInt(400000000),
+	})
+	require.NoError(t, err)
+	logs := []*types.Log{
+		{Address: params.LoanToken, Topics: []common.Hash{approvalTopic, topic(user), topic(contracts.Morpho)}, Data: word(400)},
+		{Address: params.LoanToken, Topics: []common.Hash{transferTopic, topic(user), topic(contracts.Morpho)}, Data: word(400)},
+		&supply,
+		// the accrued fee shares are minted without a Deposit
+		{Address: vault, Topics: []common.Hash{transferTopic, {}, topic(feeRecipient)}, Data: word(3)},
+		// the deposit of user mints shares to other, who then transfers some back
+		{Address: vault, Topics: []common.Hash{transferTopic, {}, topic(other)}, Data: word(30)},
+		{Address: vault, Topics: []common.Hash{depositTopic, topic(user), topic(other)}, Data: append(word(100), word(30)...)},
+		{Address: vault, Topics: []common.Hash{transferTopic, topic(other), topic(user)}, Data: word(20)},
+		// the withdrawal of user burns its shares
+		{Address: vault, Topics: []common.Hash{transferTopic, topic(user), {}}, Data: word(5)},
+		{Address: vault, Topics: []common.Hash{withdrawTopic, topic(user), topic(user), topic(user)}, Data: append(word(10), word(5)...)},
+		// ERC721 transfers index the token id and are skipped
+		{Address: params.LoanToken, Topics: []common.Hash{transferTopic, topic(user), topic(other), {1}}},
+	}
+	for i, log := range logs {
+		log.Index = uint(i)
+	}
+
+	state := newState()
+	require.NoError(t, state.ApplyReceipt(&types.Receipt{Logs: logs, BlockNumber: big.NewInt(1001)}, block, contracts))
+	require.Equal(t, block, state.Block)
+	require.Equal(t, "1700000012", state.Markets[id].LastUpdate.String())
+
+	holding := state.Holdings[user][params.LoanToken]
+	require.Equal(t, "600", holding.Balance.String())
+	require.Equal(t, map[Erc20AllowanceRecipient]uint256.Int{
+		Erc20AllowanceRecipientBundler: *uint256.NewInt(5),
+		Erc20AllowanceRecipientMorpho:  *uint256.NewInt(400),
+	}, holding.Erc20Allowances)
+	require.Equal(t, "400", state.Markets[id].TotalSupplyAssets.String())
+	require.Equal(t, "400000000", state.Positions[user][id].SupplyShares.String())
+	require.Equal(t, "25", state.VaultUsers[vault][user].Shares.String())
+	require.Equal(t, "10", state.VaultUsers[vault][other].Shares.String())
+	require.Equal(t, "3", state.VaultUsers[vault][feeRecipient].Shares.String())
+
+	t.Run("Failed receipts are not applied", func(t *testing.T) {
+		state := newState()
+		before, err := json.Marshal(state)
+		require.NoError(t, err)
+
+		overdraft := &types.Log{Address: params.LoanToken, Topics: []common.Hash{transferTopic, topic(user), topic(other)}, Data: word(2000)}
+		err = state.ApplyReceipt(&types.Receipt{Logs: []*types.Log{logs[0], overdraft}}, block, contracts)
+		require.ErrorIs(t, err, morphoblue.ErrorUint256Underflow)
+		// the morpho events are only applied if the other logs are
+		withdraw, err := morphoblue.EncodeLog(contracts.Morpho, morphoblue.WithdrawEvent{Id: id, OnBehalf: user, Shares: *uint256.NewInt(1)})
+		require.NoError(t, err)
+		err = state.ApplyReceipt(&types.Receipt{Logs: []*types.Log{logs[0], logs[1], &withdraw}}, block, contracts)
+		require.ErrorIs(t, err, morphoblue.ErrorUint256Underflow)
+		err = state.ApplyReceipt(&types.Receipt{Logs: logs, BlockNumber: big.NewInt(1002)}, block, contracts)
+		require.ErrorIs(t, err, ErrReceiptBlockMismatch)
+
+		after, err := json.Marshal(state)
+		require.NoError(t, err)
+		require.JSONEq(t, string(before), string(after))
+	})
+}